@@ -5,9 +5,17 @@ type Blocks []*Block
 
 // ParseCode turns a string of code
 // into a Block
-func ParseCode(code string) *Block {
-	lr := NewLineReader(code)
-	lr.ReadLines()
+func ParseCode(code string) (*Block, error) {
+	return ParseFileCode("", code)
+}
+
+// ParseFileCode turns a string of code read
+// from the file `fName` into a Block
+func ParseFileCode(fName string, code string) (*Block, error) {
+	lr, err := NewFileLineReader(fName, code).ReadLines()
+	if err != nil {
+		return nil, err
+	}
 
 	bb := NewBlockBuilder(lr)
 	return bb.Build()
@@ -22,10 +30,11 @@ func (blocks *Blocks) Add(b *Block) {
 // of code and handle scoping. See block types
 // for more detail
 type Block struct {
-	parent *Block
-	blocks Blocks
-	line   *Line
-	typ    blockType
+	parent    *Block
+	blocks    Blocks
+	line      *Line
+	typ       blockType
+	elseBlock *Block
 }
 
 // BlockBuilder is a struct that turns
@@ -59,6 +68,14 @@ const (
 	// each child line being its
 	// own block
 	blockTypeFor
+	// The else branch of an if block,
+	// with each child line being its
+	// own block. An elseif branch is
+	// an if block instead.
+	blockTypeElse
+	// A while loop, with each child
+	// line being its own block
+	blockTypeWhile
 )
 
 // lineBlockTypeKey is a map to help
@@ -67,8 +84,13 @@ var lineBlockTypeKey = map[lineType]blockType{
 	lineTypeFunction: blockTypeFunction,
 	lineTypeBasic:    blockTypeBasic,
 	lineTypeIf:       blockTypeIf,
+	lineTypeElseIf:   blockTypeIf,
+	lineTypeElse:     blockTypeElse,
 	lineTypeReturn:   blockTypeBasic,
 	lineTypeFor:      blockTypeFor,
+	lineTypeWhile:    blockTypeWhile,
+	lineTypeBreak:    blockTypeBasic,
+	lineTypeContinue: blockTypeBasic,
 }
 
 // NewBlock returns a new `Block`
@@ -98,12 +120,17 @@ func (b *Block) String() string {
 	switch b.typ {
 	case blockTypeBasic:
 		return b.line.String()
-	case blockTypeIf, blockTypeFunction:
-		str = b.line.String()
+	case blockTypeIf, blockTypeElse, blockTypeFunction:
+		str = b.line.String() + "\n"
 		for _, block := range b.blocks {
 			str += "\t" + block.String() + "\n"
 		}
-		str += "end"
+
+		if b.elseBlock != nil {
+			str += b.elseBlock.String()
+		} else {
+			str += "end"
+		}
 	case blockTypeMain:
 		for _, block := range b.blocks {
 			str += block.String() + "\n"
@@ -114,82 +141,237 @@ func (b *Block) String() string {
 }
 
 // Build creates the block structure
-func (bb *BlockBuilder) Build() *Block {
+func (bb *BlockBuilder) Build() (*Block, error) {
 	for bb.lr.HasNextLine() {
 		line := bb.lr.NextLine()
 
 		switch line.typ {
 		case lineTypeEnd:
+			if bb.block.parent == nil {
+				return nil, errorAt(newParseError("Unexpected end"), line.pos)
+			}
+
 			bb.depth--
 			bb.block = bb.block.parent
-		case lineTypeBasic, lineTypeReturn:
+		case lineTypeBasic, lineTypeReturn, lineTypeFunction:
+			bb.block.blocks.Add(NewBlock(bb.block, line))
+		case lineTypeBreak, lineTypeContinue:
+			if !bb.block.inLoop() {
+				return nil, errorAt(newParseError("%s outside of a loop", line.lexer.FirstItem()), line.pos)
+			}
+
 			bb.block.blocks.Add(NewBlock(bb.block, line))
-		case lineTypeIf, lineTypeFor:
+		case lineTypeIf, lineTypeFor, lineTypeWhile:
 			bb.depth++
 			newBlock := NewBlock(bb.block, line)
 			bb.block.blocks.Add(newBlock)
 			bb.block = newBlock
+		case lineTypeElseIf, lineTypeElse:
+			// Each branch is chained to the one before it
+			// and shares its parent, so the `end` of the
+			// last branch closes the whole chain
+			if bb.block.typ != blockTypeIf {
+				return nil, errorAt(newParseError("Unexpected %s", line.lexer.FirstItem()), line.pos)
+			}
+
+			newBlock := NewBlock(bb.block.parent, line)
+			bb.block.elseBlock = newBlock
+			bb.block = newBlock
 		}
 	}
 
-	return bb.block
+	if bb.block.parent != nil {
+		return nil, errorAt(newParseError("Missing end"), bb.block.line.pos)
+	}
+
+	return bb.block, nil
 }
 
-// RunBlocks runs each `Block` in the `Block` slice
-func (b *Block) RunBlocks() (Node, bool) {
+// flowType is an int representing how
+// control leaves a `Block`
+type flowType int
+
+const (
+	// Run the next block
+	flowTypeNormal flowType = iota
+	// Leave the function
+	flowTypeReturn
+	// Leave the loop
+	flowTypeBreak
+	// Start the next loop iteration
+	flowTypeContinue
+)
+
+// inLoop determines if the `Block` is
+// a loop or is inside one
+func (b *Block) inLoop() bool {
+	for ; b != nil; b = b.parent {
+		if b.typ == blockTypeFor || b.typ == blockTypeWhile {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RunBlocks runs each `Block` in the `Block` slice.
+// Function declarations are run first so functions
+// can be called before they are declared
+func (b *Block) RunBlocks(interp *Interpreter) (Node, flowType, error) {
 	var node Node
-	var returned bool
+	var flow flowType
+	var err error
+
+	for _, block := range b.blocks {
+		if block.typ == blockTypeFunction {
+			runFuncBlock(interp, block)
+		}
+	}
 
 	for _, block := range b.blocks {
-		node, returned = block.Run()
-		if returned {
-			return node, true
+		if block.typ == blockTypeFunction {
+			continue
+		}
+
+		node, flow, err = block.Run(interp)
+		if err != nil {
+			return nil, flowTypeNormal, err
+		}
+
+		if flow != flowTypeNormal {
+			return node, flow, nil
 		}
 	}
-	return node, false
+	return node, flowTypeNormal, nil
 }
 
 // Run executes the line stored in the block
-func (b *Block) Run() (Node, bool) {
+func (b *Block) Run(interp *Interpreter) (Node, flowType, error) {
 	switch b.typ {
 	case blockTypeBasic:
-		if b.line.typ == lineTypeReturn {
-			ns := b.line.NodeStream()
-			ns.Next()
-			return ns.Chop().Evaluate(), true
+		switch b.line.typ {
+		case lineTypeReturn:
+			node, err := runReturnLine(interp, b.line)
+			return node, flowTypeReturn, err
+		case lineTypeBreak:
+			return &nodeNil{}, flowTypeBreak, nil
+		case lineTypeContinue:
+			return &nodeNil{}, flowTypeContinue, nil
 		}
-		return b.line.Run(), false
+
+		node, err := b.line.Run(interp)
+		return node, flowTypeNormal, err
 	case blockTypeIf:
-		return runIfBlock(b)
+		return runIfBlock(interp, b)
+	case blockTypeElse:
+		return runElseBlock(interp, b)
 	case blockTypeFunction:
-		return runFuncBlock(b)
+		return runFuncBlock(interp, b)
 	case blockTypeFor:
-		return runForBlock(b)
+		return runForBlock(interp, b)
+	case blockTypeWhile:
+		return runWhileBlock(interp, b)
 	}
 
-	return &nodeNil{}, false
+	return &nodeNil{}, flowTypeNormal, nil
 }
 
-// runIfBlock runs an if `Block`
-func runIfBlock(b *Block) (Node, bool) {
-	Scopes.New()
-	ns := b.line.NodeStream()
+// runReturnLine evaluates the expression
+// after the `return` in a return line
+func runReturnLine(interp *Interpreter, line *Line) (Node, error) {
+	ns, err := line.NodeStream()
+	if err != nil {
+		return nil, err
+	}
+
 	ns.Next()
-	condition := ns.Chop()
+	node, err := ns.Chop().Evaluate(interp)
+	if err != nil {
+		return nil, errorAt(err, line.pos)
+	}
+
+	return node, nil
+}
+
+// conditionRPN returns the condition after the
+// first word of a block's line in RPN, so it can
+// be evaluated again with `Reset`
+func conditionRPN(b *Block) (*NodeStream, error) {
+	ns, err := b.line.NodeStream()
+	if err != nil {
+		return nil, err
+	}
+
+	ns.Next()
+	rpn, err := NewNodeStreamInRPN(ns.Chop())
+	if err != nil {
+		return nil, errorAt(err, b.line.pos)
+	}
+
+	return rpn, nil
+}
+
+// evaluateCondition evaluates a condition in
+// RPN and determines if its value is truthy
+func evaluateCondition(interp *Interpreter, b *Block, rpn *NodeStream) (bool, error) {
+	rpn.Reset()
+	condition, err := interp.EvaluateRPN(rpn)
+	if err != nil {
+		return false, errorAt(err, b.line.pos)
+	}
+
+	return isTruthy(condition), nil
+}
+
+// runIfBlock runs an if `Block`
+func runIfBlock(interp *Interpreter, b *Block) (Node, flowType, error) {
+	rpn, err := conditionRPN(b)
+	if err != nil {
+		return nil, flowTypeNormal, err
+	}
+
+	passed, err := evaluateCondition(interp, b, rpn)
+	if err != nil {
+		return nil, flowTypeNormal, err
+	}
 
-	if BooleanFromNode(condition.Evaluate()) {
-		return b.RunBlocks()
+	if passed {
+		interp.scopes.New()
+		defer interp.scopes.Pop()
+
+		return b.RunBlocks(interp)
+	}
+
+	if b.elseBlock != nil {
+		return b.elseBlock.Run(interp)
 	}
 
-	Scopes.Pop()
-	return &nodeNil{}, false
+	return &nodeNil{}, flowTypeNormal, nil
+}
+
+// runElseBlock runs an else `Block`
+func runElseBlock(interp *Interpreter, b *Block) (Node, flowType, error) {
+	interp.scopes.New()
+	defer interp.scopes.Pop()
+
+	return b.RunBlocks(interp)
 }
 
 // runForBlock runs a for `Block`
-func runForBlock(b *Block) (Node, bool) {
-	Scopes.New()
-	ns := b.line.NodeStream()
-	fd := ParseForDeclaration(ns)
+func runForBlock(interp *Interpreter, b *Block) (Node, flowType, error) {
+	ns, err := b.line.NodeStream()
+	if err != nil {
+		return nil, flowTypeNormal, err
+	}
+
+	fd, err := interp.ParseForDeclaration(ns)
+	if err != nil {
+		return nil, flowTypeNormal, errorAt(err, b.line.pos)
+	}
+
+	if fd.collection != nil {
+		return runForInLoop(interp, b, fd)
+	}
 
 	if fd.step == 0 {
 		if fd.start < fd.end {
@@ -199,18 +381,141 @@ func runForBlock(b *Block) (Node, bool) {
 		}
 	}
 
-	for i := fd.start; i <= fd.end; i += fd.step {
-		SetVar(fd.counter.name, NewNumberFromFloat(i))
-		if node, returned := b.RunBlocks(); returned {
-			return node, true
+	// A negative step counts down to the end
+	for i := fd.start; (fd.step > 0 && i <= fd.end) || (fd.step < 0 && i >= fd.end); i += fd.step {
+		counter := map[string]Node{fd.counter.name: NewNumberFromFloat(i)}
+		node, flow, err := runLoopIteration(interp, b, counter)
+		if err != nil {
+			return nil, flowTypeNormal, err
+		}
+
+		if flow == flowTypeBreak {
+			break
+		}
+
+		if flow == flowTypeReturn {
+			return node, flow, nil
+		}
+	}
+
+	return &nodeNil{}, flowTypeNormal, nil
+}
+
+// runForInLoop runs the body of a for `Block` once for
+// each item of a List or String, or key of a Map. With
+// a second variable, the counter is set to the index
+// or key and the second variable to the item or value
+func runForInLoop(interp *Interpreter, b *Block, fd *ForDeclaration) (Node, flowType, error) {
+	var keys, values []Node
+
+	switch c := fd.collection.(type) {
+	case *List:
+		values = make([]Node, len(c.values))
+		copy(values, c.values)
+	case *String:
+		for _, r := range c.value {
+			values = append(values, NewString(string(r)))
+		}
+	case *Map:
+		keys = make([]Node, len(c.keys))
+		copy(keys, c.keys)
+		for _, key := range keys {
+			value, _ := c.Get(key)
+			values = append(values, value)
+		}
+	default:
+		return nil, flowTypeNormal, errorAt(newRuntimeError("Cannot iterate over %v", fd.collection), b.line.pos)
+	}
+
+	if keys == nil {
+		keys = make([]Node, len(values))
+		for i := range values {
+			keys[i] = NewNumberFromFloat(float64(i))
+		}
+	}
+
+	for i := range values {
+		vars := make(map[string]Node)
+		switch {
+		case fd.value != nil:
+			vars[fd.counter.name] = keys[i]
+			vars[fd.value.name] = values[i]
+		case fd.collection.GetType() == nodeTypeMap:
+			vars[fd.counter.name] = keys[i]
+		default:
+			vars[fd.counter.name] = values[i]
+		}
+
+		node, flow, err := runLoopIteration(interp, b, vars)
+		if err != nil {
+			return nil, flowTypeNormal, err
+		}
+
+		if flow == flowTypeBreak {
+			break
+		}
+
+		if flow == flowTypeReturn {
+			return node, flow, nil
+		}
+	}
+
+	return &nodeNil{}, flowTypeNormal, nil
+}
+
+// runWhileBlock runs a while `Block`
+func runWhileBlock(interp *Interpreter, b *Block) (Node, flowType, error) {
+	rpn, err := conditionRPN(b)
+	if err != nil {
+		return nil, flowTypeNormal, err
+	}
+
+	for {
+		passed, err := evaluateCondition(interp, b, rpn)
+		if err != nil {
+			return nil, flowTypeNormal, err
 		}
+
+		if !passed {
+			break
+		}
+
+		node, flow, err := runLoopIteration(interp, b, nil)
+		if err != nil {
+			return nil, flowTypeNormal, err
+		}
+
+		if flow == flowTypeBreak {
+			break
+		}
+
+		if flow == flowTypeReturn {
+			return node, flow, nil
+		}
+	}
+
+	return &nodeNil{}, flowTypeNormal, nil
+}
+
+// runLoopIteration runs the body of a loop `Block` in a
+// new scope with `vars` declared on it, so each iteration
+// has its own variables for closures to keep
+func runLoopIteration(interp *Interpreter, b *Block, vars map[string]Node) (Node, flowType, error) {
+	interp.scopes.New()
+	defer interp.scopes.Pop()
+
+	for name, value := range vars {
+		interp.SetLocal(name, value)
 	}
 
-	Scopes.Pop()
-	return &nodeNil{}, false
+	return b.RunBlocks(interp)
 }
 
-// runFuncBlock is currently not a thing
-func runFuncBlock(b *Block) (Node, bool) {
-	return &nodeNil{}, false
+// runFuncBlock declares the function stored in a
+// function `Block` as a closure over the current scope
+func runFuncBlock(interp *Interpreter, b *Block) (Node, flowType, error) {
+	f := *b.line.function
+	f.closure = interp.CurrScope()
+	interp.SetFunc(f.name, &f)
+	return &nodeNil{}, flowTypeNormal, nil
 }
@@ -0,0 +1,179 @@
+package blast
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIfElseBlocks(t *testing.T) {
+	interp := NewInterpreter()
+
+	_, err := interp.Run(strings.Join([]string{
+		"function grade(score)",
+		"  if score >= 90",
+		"    return \"A\"",
+		"  elseif score >= 80",
+		"    return \"B\"",
+		"  elseif score >= 70",
+		"    return \"C\"",
+		"  else",
+		"    return \"F\"",
+		"  end",
+		"end",
+	}, "\n"))
+	assert.Nil(t, err)
+
+	for score, grade := range map[float64]string{95: "A", 85: "B", 70: "C", 12: "F"} {
+		result, err := interp.Call("grade", score)
+		assert.Nil(t, err)
+		assertStringFromNode(t, grade, result)
+	}
+
+	result, err := interp.Run("x = 0\nif false\n  x = 1\nelse\n  x = 2\nend\nx")
+	assert.Nil(t, err)
+	assertFloat64FromNode(t, 2, result)
+
+	result, err = interp.Run("if true\n  x = 3\nelse\n  x = 4\nend\nx")
+	assert.Nil(t, err)
+	assertFloat64FromNode(t, 3, result)
+
+	// Conditions use the same truthiness as && and ||
+	result, err = interp.Run("x = 0\nif \"a\"\n  x = 5\nend\nif 0\n  x = 6\nend\nx")
+	assert.Nil(t, err)
+	assertFloat64FromNode(t, 5, result)
+
+	result, err = interp.Run("n = 3\nwhile n\n  n -= 1\nend\nn")
+	assert.Nil(t, err)
+	assertFloat64FromNode(t, 0, result)
+}
+
+func TestIfElseErrors(t *testing.T) {
+	_, err := ParseCode("x = 1\nelse\n  x = 2\nend")
+	assert.IsType(t, &ErrParse{}, err)
+	assert.Equal(t, 2, err.(Error).Position().Line)
+
+	_, err = ParseCode("if x\nelse\nelseif y\nend")
+	assert.IsType(t, &ErrParse{}, err)
+	assert.Equal(t, 3, err.(Error).Position().Line)
+
+	_, err = ParseCode("if x\nelse\n  y = 1\n")
+	assert.IsType(t, &ErrParse{}, err)
+}
+
+func TestWhileBlocks(t *testing.T) {
+	interp := NewInterpreter()
+
+	result, err := interp.Run(strings.Join([]string{
+		"n = 1",
+		"while n < 100",
+		"  n = n * 2",
+		"end",
+		"n",
+	}, "\n"))
+	assert.Nil(t, err)
+	assertFloat64FromNode(t, 128, result)
+
+	result, err = interp.Run(strings.Join([]string{
+		"sum = 0",
+		"i = 0",
+		"while true",
+		"  i = i + 1",
+		"  if i > 10",
+		"    break",
+		"  end",
+		"",
+		"  if i % 2 == 0",
+		"    continue",
+		"  end",
+		"",
+		"  sum = sum + i",
+		"end",
+		"sum",
+	}, "\n"))
+	assert.Nil(t, err)
+	assertFloat64FromNode(t, 25, result)
+	assert.Equal(t, 1, interp.scopes.size)
+}
+
+func TestForBreakContinue(t *testing.T) {
+	interp := NewInterpreter()
+
+	result, err := interp.Run(strings.Join([]string{
+		"function firstOver(limit)",
+		"  for 1 -> 100, i",
+		"    if i * i > limit",
+		"      return i",
+		"    end",
+		"  end",
+		"",
+		"  return 0",
+		"end",
+		"",
+		"sum = 0",
+		"for 1 -> 20, i",
+		"  if i % 3 > 0",
+		"    continue",
+		"  elseif i > 10",
+		"    break",
+		"  end",
+		"",
+		"  sum = sum + i",
+		"end",
+		"sum + firstOver(50)",
+	}, "\n"))
+	assert.Nil(t, err)
+	assertFloat64FromNode(t, 26, result)
+	assert.Equal(t, 1, interp.scopes.size)
+
+	_, err = ParseCode("if true\n  break\nend")
+	assert.IsType(t, &ErrParse{}, err)
+	assert.Equal(t, "2: break outside of a loop", err.Error())
+
+	_, err = ParseCode("while true\n  function f()\n    continue\n  end\nend")
+	assert.IsType(t, &ErrParse{}, err)
+}
+
+func TestForInLoops(t *testing.T) {
+	interp := NewInterpreter()
+
+	result, err := interp.Run(strings.Join([]string{
+		"out = []",
+		"for x in [1, 2, 3]",
+		"  push(out, x * 10)",
+		"end",
+		"for i, c in \"hé\"",
+		"  push(out, i, c)",
+		"end",
+		"for key in {\"a\": 1, \"b\": 2}",
+		"  push(out, key)",
+		"end",
+		"for key, value in {\"c\": 3}",
+		"  push(out, key, value)",
+		"end",
+		"out",
+	}, "\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, "[10, 20, 30, 0, \"h\", 1, \"é\", \"a\", \"b\", \"c\", 3]", result.String())
+
+	// Items pushed while looping are not visited
+	result, err = interp.Run(strings.Join([]string{
+		"xs = [1, 2, 3, 4]",
+		"sum = 0",
+		"for x in xs",
+		"  push(xs, x)",
+		"  if x == 3",
+		"    break",
+		"  end",
+		"  sum = sum + x",
+		"end",
+		"sum",
+	}, "\n"))
+	assert.Nil(t, err)
+	assertFloat64FromNode(t, 3, result)
+	assert.Equal(t, 1, interp.scopes.size)
+
+	_, err = interp.Run("for x in 5\nend")
+	assert.EqualError(t, err, "1: Cannot iterate over 5")
+}
@@ -0,0 +1,177 @@
+package blast
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pos is a position in blast source code.
+// An empty File or a zero Line or Col
+// means it is unknown.
+type Pos struct {
+	File string
+	Line int
+	Col  int
+}
+
+// String returns a Pos as file:line:col
+func (p Pos) String() string {
+	str := fmt.Sprintf("%d", p.Line)
+
+	if p.File != "" {
+		str = p.File + ":" + str
+	}
+
+	if p.Col != 0 {
+		str += fmt.Sprintf(":%d", p.Col)
+	}
+
+	return str
+}
+
+// IsValid determines if the Pos
+// points to a line of code
+func (p Pos) IsValid() bool {
+	return p.Line > 0
+}
+
+// Error is implemented by every error
+// produced while lexing, parsing or
+// running blast code
+type Error interface {
+	error
+	Position() Pos
+	Frames() []Frame
+	Traceback() string
+}
+
+// blastError stores the position, cause and call
+// stack shared by all of the blast error types
+type blastError struct {
+	pos    Pos
+	err    error
+	frames []Frame
+}
+
+// Error returns the cause prefixed
+// with the position if it is known
+func (e *blastError) Error() string {
+	if e.pos.IsValid() {
+		return e.pos.String() + ": " + e.err.Error()
+	}
+
+	return e.err.Error()
+}
+
+// Position returns where the error occurred
+func (e *blastError) Position() Pos {
+	return e.pos
+}
+
+// Frames returns the function calls that were on
+// the call stack when the error occurred, with
+// the most recent call first
+func (e *blastError) Frames() []Frame {
+	frames := make([]Frame, len(e.frames))
+
+	for i, frame := range e.frames {
+		frames[len(frames)-1-i] = frame
+	}
+
+	return frames
+}
+
+// Traceback returns the error followed by
+// the function calls that led to it
+func (e *blastError) Traceback() string {
+	str := e.Error()
+
+	if len(e.frames) == 0 {
+		return str
+	}
+
+	lines := []string{str, "stack traceback:"}
+	frames := e.Frames()
+
+	for i, frame := range frames {
+		// Skip the middle of very deep call stacks
+		if i == maxTracebackFrames && len(frames) > 2*maxTracebackFrames {
+			skipped := len(frames) - 2*maxTracebackFrames
+			lines = append(lines, fmt.Sprintf("\t...(%d more)", skipped))
+		}
+
+		if i >= maxTracebackFrames && i < len(frames)-maxTracebackFrames {
+			continue
+		}
+
+		lines = append(lines, "\t"+frame.String())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Unwrap returns the cause of the error
+func (e *blastError) Unwrap() error {
+	return e.err
+}
+
+// setPos sets the position of the error
+// if it is not already known
+func (e *blastError) setPos(pos Pos) {
+	if !e.pos.IsValid() {
+		e.pos = pos
+	}
+}
+
+// ErrLex is returned when the Lexer
+// runs into text it cannot lex
+type ErrLex struct {
+	blastError
+}
+
+// ErrParse is returned when lexed code
+// does not form a valid statement
+type ErrParse struct {
+	blastError
+}
+
+// ErrRuntime is returned when
+// evaluating code fails
+type ErrRuntime struct {
+	blastError
+}
+
+// newLexError returns a new ErrLex
+func newLexError(pos Pos, errFmt string, args ...interface{}) *ErrLex {
+	return &ErrLex{blastError{pos: pos, err: fmt.Errorf(errFmt, args...)}}
+}
+
+// newParseError returns a new ErrParse
+// without a position
+func newParseError(errFmt string, args ...interface{}) *ErrParse {
+	return &ErrParse{blastError{err: fmt.Errorf(errFmt, args...)}}
+}
+
+// newRuntimeError returns a new ErrRuntime
+// without a position
+func newRuntimeError(errFmt string, args ...interface{}) *ErrRuntime {
+	return &ErrRuntime{blastError{err: fmt.Errorf(errFmt, args...)}}
+}
+
+// errorAt sets the position of `err` if it is a blast
+// error without one, or wraps any other error in an
+// ErrRuntime at that position
+func errorAt(err error, pos Pos) error {
+	switch e := err.(type) {
+	case *ErrLex:
+		e.setPos(pos)
+	case *ErrParse:
+		e.setPos(pos)
+	case *ErrRuntime:
+		e.setPos(pos)
+	default:
+		return &ErrRuntime{blastError{pos: pos, err: err}}
+	}
+
+	return err
+}
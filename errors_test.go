@@ -0,0 +1,58 @@
+package blast
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseErrors(t *testing.T) {
+	_, err := ParseCode("x = 1\ny = 2 @ 3")
+	assert.IsType(t, &ErrLex{}, err)
+	assert.Equal(t, 2, err.(Error).Position().Line)
+
+	_, err = ParseCode("x = 1\nend")
+	assert.IsType(t, &ErrParse{}, err)
+	assert.Equal(t, "2: Unexpected end", err.Error())
+
+	_, err = ParseCode("if x\n  y = 1\n")
+	assert.IsType(t, &ErrParse{}, err)
+	assert.Equal(t, 1, err.(Error).Position().Line)
+}
+
+func TestRuntimeErrors(t *testing.T) {
+	interp := NewInterpreter()
+
+	block, err := ParseCode("x = 1\n\ny = x - \"derp\"")
+	assert.Nil(t, err)
+
+	_, _, err = block.RunBlocks(interp)
+	assert.IsType(t, &ErrRuntime{}, err)
+	assert.Equal(t, "3:7: Cannot subtract 1 and \"derp\"", err.Error())
+
+	block, err = ParseCode("function f(n)\n  return n + z\nend\nf(1)")
+	assert.Nil(t, err)
+
+	_, _, err = block.RunBlocks(interp)
+	assert.IsType(t, &ErrRuntime{}, err)
+	assert.Equal(t, "2:14: Variable z not found", err.Error())
+}
+
+func TestErrorPositions(t *testing.T) {
+	_, err := ParseFileCode("program.blast", "x = 1\n\ty = x @ 2")
+	assert.Equal(t, "program.blast:2:8: Unexpected character '@'", err.Error())
+
+	block, err := ParseFileCode("program.blast", "x = 1\n\ny = 4 * (x - \"a\")")
+	assert.Nil(t, err)
+
+	_, _, err = block.RunBlocks(NewInterpreter())
+	assert.Equal(t, Pos{File: "program.blast", Line: 3, Col: 12}, err.(Error).Position())
+	assert.Equal(t, "program.blast:3:12: Cannot subtract 1 and \"a\"", err.Error())
+}
+
+func TestUnmatchedGroupErrors(t *testing.T) {
+	for _, code := range []string{"f(", "x = [", "{", "(", "x = {", "f(1,", "[1,", "xs = [1]\nxs["} {
+		_, err := NewInterpreter().Run(code)
+		assert.IsType(t, &ErrParse{}, err, code)
+	}
+}
@@ -0,0 +1,90 @@
+package blast
+
+import "strings"
+
+const (
+	// maxCallDepth is the most function calls
+	// that can be on the call stack at once
+	maxCallDepth = 10000
+	// maxTracebackFrames is the most Frames
+	// shown at each end of a traceback
+	maxTracebackFrames = 10
+)
+
+// Frame is a function call on
+// an Interpreter's call stack
+type Frame struct {
+	Name string
+	Pos  Pos
+	Args []Node
+}
+
+// String returns the Frame as the position
+// of the call and the call itself
+func (f Frame) String() string {
+	args := make([]string, len(f.Args))
+
+	for i, arg := range f.Args {
+		args[i] = arg.String()
+	}
+
+	call := "in " + f.Name + "(" + strings.Join(args, ", ") + ")"
+
+	if !f.Pos.IsValid() {
+		return call
+	}
+
+	return f.Pos.String() + ": " + call
+}
+
+// pushFrame adds a Frame to the call stack
+func (interp *Interpreter) pushFrame(name string, pos Pos, args *NodeStream) error {
+	if len(interp.frames) >= maxCallDepth {
+		return newRuntimeError("Stack overflow calling %s", name)
+	}
+
+	frame := Frame{Name: name, Pos: pos}
+	frame.Args = append(frame.Args, args.nodes...)
+	interp.frames = append(interp.frames, frame)
+	return nil
+}
+
+// popFrame removes the Frame at the
+// top of the call stack
+func (interp *Interpreter) popFrame() {
+	interp.frames = interp.frames[:len(interp.frames)-1]
+}
+
+// truncateFrames removes Frames from the top of
+// the call stack until only `size` are left
+func (interp *Interpreter) truncateFrames(size int) {
+	if size < len(interp.frames) {
+		interp.frames = interp.frames[:size]
+	}
+}
+
+// traceError attaches a copy of the call stack to `err`
+// if it does not already have one, so the trace shows
+// where the error happened rather than where it was
+// caught
+func (interp *Interpreter) traceError(err error) error {
+	var e *blastError
+
+	switch err := err.(type) {
+	case *ErrLex:
+		e = &err.blastError
+	case *ErrParse:
+		e = &err.blastError
+	case *ErrRuntime:
+		e = &err.blastError
+	default:
+		return err
+	}
+
+	if e.frames == nil {
+		e.frames = make([]Frame, len(interp.frames))
+		copy(e.frames, interp.frames)
+	}
+
+	return err
+}
@@ -0,0 +1,60 @@
+package blast
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceback(t *testing.T) {
+	interp := NewInterpreter()
+	code := strings.Join([]string{
+		"function countdown(n)",
+		"  if n == 0",
+		"    return boom",
+		"  end",
+		"",
+		"  return countdown(n - 1)",
+		"end",
+		"",
+		"countdown(2)",
+	}, "\n")
+
+	block, err := ParseFileCode("program.blast", code)
+	assert.Nil(t, err)
+
+	_, _, err = block.RunBlocks(interp)
+	assert.IsType(t, &ErrRuntime{}, err)
+	assert.Equal(t, 3, len(err.(Error).Frames()))
+	assert.Equal(t, strings.Join([]string{
+		"program.blast:3:12: Variable boom not found",
+		"stack traceback:",
+		"\tprogram.blast:6:10: in countdown(0)",
+		"\tprogram.blast:6:10: in countdown(1)",
+		"\tprogram.blast:9:1: in countdown(2)",
+	}, "\n"), err.(Error).Traceback())
+
+	// The call stack is empty after the error
+	assert.Equal(t, 0, len(interp.frames))
+}
+
+func TestCallTraceback(t *testing.T) {
+	interp := NewInterpreter()
+
+	_, err := interp.Run("function half(n)\n  return n / \"two\"\nend")
+	assert.Nil(t, err)
+
+	_, err = interp.Call("half", 4)
+	assert.Equal(t, "2:12: Cannot divide 4 and \"two\"\nstack traceback:\n\tin half(4)",
+		err.(Error).Traceback())
+}
+
+func TestStackOverflow(t *testing.T) {
+	interp := NewInterpreter()
+
+	_, err := interp.Run("function forever(n)\n  return forever(n + 1)\nend\nforever(1)")
+	assert.IsType(t, &ErrRuntime{}, err)
+	assert.Contains(t, err.Error(), "Stack overflow")
+	assert.Contains(t, err.(Error).Traceback(), "...(9980 more)")
+}
@@ -5,7 +5,7 @@ import "fmt"
 // Function is an interface
 // with a call method
 type Function interface {
-	Call(args *NodeStream) Node
+	Call(interp *Interpreter, args *NodeStream) (Node, error)
 }
 
 // funcNil is returned when there
@@ -17,22 +17,53 @@ var funcNil = &UserFunction{}
 // that represents a user
 // defined function
 type UserFunction struct {
-	params []*Param
-	name   string
-	block  *Block
+	params  []*Param
+	name    string
+	block   *Block
+	body    *NodeStream
+	closure *Scope
+}
+
+// FunctionValue is a Node that stores a
+// Function, so functions can be stored in
+// variables, passed and returned
+type FunctionValue struct {
+	nodePos
+	name string
+	f    Function
+}
+
+// GetType returns nodeTypeFunction
+func (fv *FunctionValue) GetType() nodeType {
+	return nodeTypeFunction
+}
+
+// String returns the function name
+// wrapped in angle brackets
+func (fv *FunctionValue) String() string {
+	return fmt.Sprintf("<function %s>", fv.name)
+}
+
+// NewFunctionValue returns a new FunctionValue
+func NewFunctionValue(name string, f Function) *FunctionValue {
+	return &FunctionValue{name: name, f: f}
 }
 
 // Param is a struct that
 // represents a function
 // definition parameter
 type Param struct {
-	name  string
-	value Node
+	name     string
+	value    Node
+	rpn      *NodeStream
+	required bool
+	variadic bool
 }
 
-// goFunc is a func type with a NodeStream
-// parameter that returns an interface
-type goFunc func(args *NodeStream) interface{}
+// goFunc is a func type with an Interpreter and
+// NodeStream parameter that returns an interface
+// or an error
+type goFunc func(interp *Interpreter, args *NodeStream) (interface{}, error)
 
 // BuilinFunction is a struct represeting
 // a function built in to Blast that
@@ -42,112 +73,316 @@ type BuiltinFunction struct {
 }
 
 // Call runs a UserFunction and returns the
-// result as a odSe
-func (f *UserFunction) Call(args *NodeStream) Node {
-	Scopes.New()
+// result as a odSe. Its scope is nested in
+// the scope it was declared in
+func (f *UserFunction) Call(interp *Interpreter, args *NodeStream) (Node, error) {
+	closure := f.closure
+	if closure == nil {
+		closure = interp.GlobalScope()
+	}
+
+	interp.scopes.NewFunction(closure)
+	defer interp.scopes.Pop()
+
+	if err := f.bindArgs(interp, args); err != nil {
+		return nil, err
+	}
+
+	// An anonymous function's body is
+	// an expression already in RPN
+	if f.body != nil {
+		return interp.EvaluateRPN(f.body.Clone())
+	}
+
+	result, _, err := f.block.RunBlocks(interp)
+	if err != nil {
+		return nil, err
+	}
+
+	if result == nil {
+		return &nodeNil{}, nil
+	}
+
+	return result, nil
+}
+
+// bindArgs declares each parameter on the current
+// scope, from the argument passed by position or by
+// name, or else from its default value. A variadic
+// parameter is a List of the extra arguments
+func (f *UserFunction) bindArgs(interp *Interpreter, args *NodeStream) error {
+	values := make(map[string]Node)
+	rest := []Node{}
+	position, named := 0, 0
+
+	for _, arg := range args.nodes {
+		namedArg, ok := arg.(*NamedArg)
+		if !ok {
+			if named > 0 {
+				return newRuntimeError("Positional argument %v after named arguments in call to %s", arg, f.name)
+			}
+
+			if position < len(f.params) && !f.params[position].variadic {
+				values[f.params[position].name] = arg
+				position++
+			} else {
+				rest = append(rest, arg)
+			}
+
+			continue
+		}
+
+		named++
+		param := f.param(namedArg.name)
+		switch {
+		case param == nil:
+			return errorAt(newRuntimeError("Unknown argument %s in call to %s", namedArg.name, f.name), namedArg.Pos())
+		case param.variadic:
+			return errorAt(newRuntimeError("Cannot pass variadic argument %s by name in call to %s", namedArg.name, f.name), namedArg.Pos())
+		}
+
+		if _, ok := values[namedArg.name]; ok {
+			return errorAt(newRuntimeError("Duplicate argument %s in call to %s", namedArg.name, f.name), namedArg.Pos())
+		}
+
+		values[namedArg.name] = namedArg.value
+	}
+
+	// Named arguments can fill parameters out of
+	// order, so only too many can be counted
+	min, max := f.arity()
+	if named == 0 || (max != -1 && args.Length() > max) {
+		if err := checkArgCount(f.name, args, min, max); err != nil {
+			return err
+		}
+	}
 
 	for _, param := range f.params {
-		if args.HasNext() {
-			arg := args.Next()
-			SetVar(param.name, arg)
-		} else {
-			SetVar(param.name, param.value)
+		value, ok := values[param.name]
+		switch {
+		case param.variadic:
+			value = NewList(rest)
+		case !ok && param.required:
+			return newRuntimeError("Missing argument %s in call to %s", param.name, f.name)
+		case !ok && param.rpn != nil:
+			var err error
+			if value, err = interp.EvaluateRPN(param.rpn.Clone()); err != nil {
+				return err
+			}
+		case !ok:
+			value = param.value
 		}
+
+		interp.SetLocal(param.name, value)
 	}
 
-	result, _ := f.block.RunBlocks()
-	Scopes.Pop()
-	return result
+	return nil
 }
 
-// Call runs a BuiltinFunction and returns the result as a Node
-func (bf *BuiltinFunction) Call(args *NodeStream) Node {
-	result := bf.f(args)
+// arity returns the least and most arguments the
+// UserFunction can be called with by position. The
+// most is -1 if it has a variadic parameter
+func (f *UserFunction) arity() (int, int) {
+	min, max := 0, len(f.params)
+
+	for i, param := range f.params {
+		switch {
+		case param.variadic:
+			max = -1
+		case param.required:
+			min = i + 1
+		}
+	}
 
-	if result == nil {
-		return &nodeNil{}
+	return min, max
+}
+
+// param returns the parameter of the UserFunction
+// called `name`, or nil if there is none
+func (f *UserFunction) param(name string) *Param {
+	for _, param := range f.params {
+		if param.name == name {
+			return param
+		}
+	}
+
+	return nil
+}
+
+// Call runs a BuiltinFunction and returns the result as a Node
+func (bf *BuiltinFunction) Call(interp *Interpreter, args *NodeStream) (Node, error) {
+	for _, arg := range args.nodes {
+		if named, ok := arg.(*NamedArg); ok {
+			return nil, errorAt(newRuntimeError("Builtin functions do not take named arguments, got %v", named), named.Pos())
+		}
 	}
 
-	switch result.(type) {
-	case Node:
-		return result.(Node)
-	case float64:
-		return NewNumberFromFloat(result.(float64))
-	case string:
-		return NewString(result.(string))
-	case bool:
-		return NewBooleanFromBool(result.(bool))
+	result, err := bf.f(interp, args)
+
+	if err != nil {
+		return nil, err
 	}
 
-	return &nodeNil{}
+	return NewNodeFromValue(result)
 }
 
 // ParseUserFunction parses a NodeStream into a user
 // function definition
-func ParseUserFunction(ns *NodeStream) *UserFunction {
-	parenDepth := 1
+func ParseUserFunction(ns *NodeStream) (*UserFunction, error) {
+	var err error
 	f := new(UserFunction)
-	paramns := NewNodeStream()
 
 	// Skip the "function"
 	ns.Next()
 
 	// Set the name
-	f.name = ns.Next().(*FunctionCall).name
+	funcCall, ok := ns.Next().(*FunctionCall)
+	if !ok {
+		return nil, newParseError("Expected name and parameters in function declaration")
+	}
+
+	f.name = funcCall.name
 
 	// Skip the first paren
 	ns.Next()
 
+	if f.params, err = parseParams(ns); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// parseParams parses the parameters of a function
+// declaration after its first paren, up to and
+// including the paren that closes them
+func parseParams(ns *NodeStream) ([]*Param, error) {
+	var params []*Param
+	parenDepth := 1
+	paramns := NewNodeStream()
+
 	for ns.HasNext() {
 		node := ns.Next()
-		if node.GetType() == nodeTypeParen {
-			switch paren := node.(*Paren); paren.typ {
-			case parenTypeOpen:
-				parenDepth++
-			case parenTypeClose:
-				parenDepth--
-			}
+		switch getGroupType(node) {
+		case parenTypeOpen:
+			parenDepth++
+		case parenTypeClose:
+			parenDepth--
 		}
 
 		if parenDepth == 0 {
 			if paramns.Length() > 0 {
-				f.params = append(f.params, ParseParam(paramns))
+				param, err := ParseParam(paramns)
+				if err != nil {
+					return nil, err
+				}
+
+				params = append(params, param)
 			}
-			break
+
+			return params, checkVariadic(params)
 		}
 
-		if node.GetType() == nodeTypeComma {
-			f.params = append(f.params, ParseParam(paramns))
+		if parenDepth == 1 && node.GetType() == nodeTypeComma {
+			param, err := ParseParam(paramns)
+			if err != nil {
+				return nil, err
+			}
+
+			params = append(params, param)
 			paramns = NewNodeStream()
 		} else {
 			paramns.Push(node)
 		}
 	}
 
-	return f
+	return nil, newParseError("Expected ) after parameters")
+}
+
+// checkVariadic returns an error if a variadic
+// parameter is not the last of `params`
+func checkVariadic(params []*Param) error {
+	for i, param := range params {
+		if param.variadic && i != len(params)-1 {
+			return newParseError("Variadic parameter %s must be last", param.name)
+		}
+	}
+
+	return nil
 }
 
 // ParseParam parses a NodeStream into
 // a parameter
-func ParseParam(ns *NodeStream) *Param {
+func ParseParam(ns *NodeStream) (*Param, error) {
+	var err error
 	param := new(Param)
 
-	if ns.Length() == 1 {
-		return &Param{
-			name:  ns.Next().String(),
-			value: &nodeNil{},
-		}
+	if isReserved(ns.Peek(), "...") {
+		ns.Next()
+		param.variadic = true
 	}
 
+	if !ns.HasNext() || ns.Peek().GetType() != nodeTypeVariable {
+		return nil, newParseError("Expected parameter name in function declaration")
+	}
+
+	// Without a default value it
+	// must be passed an argument
 	param.name = ns.Next().String()
+	if !ns.HasNext() {
+		param.value = &nodeNil{}
+		param.required = !param.variadic
+		return param, nil
+	}
+
+	if param.variadic {
+		return nil, newParseError("Variadic parameter %s cannot have a default value", param.name)
+	}
 
 	if ns.Peek().GetType() == nodeTypeOperator &&
 		ns.Peek().(*Operator).typ == opTypeAssignment {
 		ns.Next()
 	}
 
-	param.value = ns.Chop().Evaluate()
-	return param
+	rpn, err := NewNodeStreamInRPN(ns.Chop())
+	if err != nil {
+		return nil, err
+	}
+
+	// A constant default needs no scope or builtins,
+	// so it is evaluated once. Anything else is
+	// evaluated on each call, so that each one
+	// gets its own Lists and Maps
+	if !isConstant(rpn) {
+		param.value = &nodeNil{}
+		param.rpn = rpn
+		return param, nil
+	}
+
+	if param.value, err = new(Interpreter).EvaluateRPN(rpn); err != nil {
+		return nil, err
+	}
+
+	return param, nil
+}
+
+// isConstant determines if the RPN expression
+// `rpn` only has literals and operators, so
+// it always has the same value
+func isConstant(rpn *NodeStream) bool {
+	for _, node := range rpn.nodes {
+		switch n := node.(type) {
+		case *Number, *String, *Boolean, *ShortCircuit:
+		case *Operator:
+			if isAssignment(n) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+
+	return true
 }
 
 // NewBuiltinFunction returns a new BuiltinFunction
@@ -158,38 +393,119 @@ func NewBuiltinFunc(f goFunc) *BuiltinFunction {
 }
 
 // LoadBuiltinFunctions adds all the BuiltinFuctions
-// to the scope
-func LoadBuiltinFunctions() {
-	SetFunc("print", NewBuiltinFunc(builtinPrint))
-	SetFunc("println", NewBuiltinFunc(builtinPrintln))
+// to the Interpreter
+func (interp *Interpreter) LoadBuiltinFunctions() {
+	interp.builtins["print"] = NewBuiltinFunc(builtinPrint)
+	interp.builtins["println"] = NewBuiltinFunc(builtinPrintln)
+	interp.builtins["len"] = NewBuiltinFunc(builtinLen)
+	interp.builtins["push"] = NewBuiltinFunc(builtinPush)
+	interp.builtins["pop"] = NewBuiltinFunc(builtinPop)
+	interp.builtins["slice"] = NewBuiltinFunc(builtinSlice)
+	interp.builtins["concat"] = NewBuiltinFunc(builtinConcat)
+	interp.builtins["range"] = NewBuiltinFunc(builtinRange)
+	interp.builtins["keys"] = NewBuiltinFunc(builtinKeys)
+	interp.builtins["values"] = NewBuiltinFunc(builtinValues)
+	interp.builtins["has"] = NewBuiltinFunc(builtinHas)
+	interp.builtins["delete"] = NewBuiltinFunc(builtinDelete)
+	interp.builtins["upper"] = NewBuiltinFunc(builtinUpper)
+	interp.builtins["lower"] = NewBuiltinFunc(builtinLower)
+	interp.builtins["trim"] = NewBuiltinFunc(builtinTrim)
+	interp.builtins["split"] = NewBuiltinFunc(builtinSplit)
+	interp.builtins["join"] = NewBuiltinFunc(builtinJoin)
+	interp.builtins["replace"] = NewBuiltinFunc(builtinReplace)
+	interp.builtins["contains"] = NewBuiltinFunc(builtinContains)
+	interp.builtins["starts_with"] = NewBuiltinFunc(builtinStartsWith)
+	interp.builtins["ends_with"] = NewBuiltinFunc(builtinEndsWith)
+	interp.builtins["index_of"] = NewBuiltinFunc(builtinIndexOf)
+	interp.builtins["substring"] = NewBuiltinFunc(builtinSubstring)
+	interp.builtins["repeat"] = NewBuiltinFunc(builtinRepeat)
+	interp.builtins["char"] = NewBuiltinFunc(builtinChar)
+	interp.builtins["ord"] = NewBuiltinFunc(builtinOrd)
+	interp.builtins["sqrt"] = NewBuiltinFunc(builtinSqrt)
+	interp.builtins["abs"] = NewBuiltinFunc(builtinAbs)
+	interp.builtins["floor"] = NewBuiltinFunc(builtinFloor)
+	interp.builtins["ceil"] = NewBuiltinFunc(builtinCeil)
+	interp.builtins["round"] = NewBuiltinFunc(builtinRound)
+	interp.builtins["min"] = NewBuiltinFunc(builtinMin)
+	interp.builtins["max"] = NewBuiltinFunc(builtinMax)
+	interp.builtins["sin"] = NewBuiltinFunc(builtinSin)
+	interp.builtins["cos"] = NewBuiltinFunc(builtinCos)
+	interp.builtins["tan"] = NewBuiltinFunc(builtinTan)
+	interp.builtins["atan2"] = NewBuiltinFunc(builtinAtan2)
+	interp.builtins["log"] = NewBuiltinFunc(builtinLog)
+	interp.builtins["exp"] = NewBuiltinFunc(builtinExp)
+	interp.builtins["pi"] = NewBuiltinFunc(builtinPi)
+	interp.builtins["e"] = NewBuiltinFunc(builtinE)
+	interp.builtins["inf"] = NewBuiltinFunc(builtinInf)
 }
 
-// builtinPrint prinns the Nodes
-func builtinPrint(args *NodeStream) interface{} {
-	str := ""
+// checkArgCount returns an error if the number
+// of `args` passed to the builtin `name` is not
+// from `min` to `max`. A `max` of -1 is no limit
+func checkArgCount(name string, args *NodeStream, min int, max int) error {
+	count := args.Length()
+
+	switch {
+	case count >= min && (count <= max || max == -1):
+		return nil
+	case min == max:
+		return newRuntimeError("%s expects %s, got %d", name, argumentCount(min), count)
+	case max == -1:
+		return newRuntimeError("%s expects at least %s, got %d", name, argumentCount(min), count)
+	}
 
-	for _, node := range args.nodes {
-		str += StringFromNode(node) + " "
+	return newRuntimeError("%s expects %d to %s, got %d", name, min, argumentCount(max), count)
+}
+
+// argumentCount returns `count` followed
+// by argument or arguments
+func argumentCount(count int) string {
+	if count == 1 {
+		return "1 argument"
 	}
 
-	str = str[:len(str)-1]
-	fmt.Print(str)
+	return fmt.Sprintf("%d arguments", count)
+}
 
-	return nil
+// builtinPrint prinns the Nodes
+func builtinPrint(interp *Interpreter, args *NodeStream) (interface{}, error) {
+	str, err := joinNodeStrings(args)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprint(interp.out, str)
+	return nil, nil
 }
 
 // builtinPrint prinns the Nodes on their own line
-func builtinPrintln(args *NodeStream) interface{} {
+func builtinPrintln(interp *Interpreter, args *NodeStream) (interface{}, error) {
+	str, err := joinNodeStrings(args)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintln(interp.out, str)
+	return nil, nil
+}
+
+// joinNodeStrings returns the string values
+// of the Nodes separated by spaces
+func joinNodeStrings(args *NodeStream) (string, error) {
 	str := ""
 
 	for _, node := range args.nodes {
-		str += StringFromNode(node) + " "
+		nodeStr, err := StringFromNode(node)
+		if err != nil {
+			return "", err
+		}
+
+		str += nodeStr + " "
 	}
 
 	if len(str) > 0 {
 		str = str[:len(str)-1]
 	}
 
-	fmt.Println(str)
-	return nil
+	return str, nil
 }
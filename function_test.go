@@ -1,23 +1,354 @@
 package blast
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestFunctionParsing(t *testing.T) {
-	f := ParseUserFunction(NewNodeStreamFromLexer(
-		Lex("function max(x = 200, y, z = .9 * (44.4 + 14))")))
+	f, err := ParseUserFunction(lexNodeStream(t,
+		"function max(x = 200, y, z = .9 * (44.4 + 14))"))
+	assert.Nil(t, err)
 
 	assert.Equal(t, "max", f.name)
 
 	assert.Equal(t, "x", f.params[0].name)
-	assert.Equal(t, NewNumberFromFloat(200.0), f.params[0].value.(*Number))
+	assert.Equal(t, 200.0, f.params[0].value.(*Number).value)
 
 	assert.Equal(t, "y", f.params[1].name)
 	assert.IsType(t, &nodeNil{}, f.params[1].value)
 
 	assert.Equal(t, "z", f.params[2].name)
-	assert.Equal(t, NewNumberFromFloat(52.56), f.params[2].value.(*Number))
+	assert.Equal(t, 52.56, f.params[2].value.(*Number).value)
+}
+
+func TestFunctionValues(t *testing.T) {
+	interp := NewInterpreter()
+
+	result, err := interp.Run(strings.Join([]string{
+		"function add(a, b)",
+		"  return a + b",
+		"end",
+		"function apply(f, a, b)",
+		"  return f(a, b)",
+		"end",
+		"function getAdd()",
+		"  return add",
+		"end",
+		"ops = {\"add\": add}",
+		"f = add",
+		"[f(1, 2), apply(add, 3, 4), getAdd()(5, 6), ops[\"add\"](7, 8), [f][0](1, 1)]",
+	}, "\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, "[3, 7, 11, 15, 2]", result.String())
+
+	result, err = interp.Run("[f == add, f == print, len, f]")
+	assert.Nil(t, err)
+	assert.Equal(t, "[true, false, <function len>, <function add>]", result.String())
+
+	result, err = interp.Call("f", 2, 2)
+	assert.Nil(t, err)
+	assertFloat64FromNode(t, 4, result)
+
+	_, err = interp.Run("x = 1\nx(2)")
+	assert.EqualError(t, err, "2:1: x is 1, not a function")
+
+	_, err = interp.Run("[1](2)")
+	assert.EqualError(t, err, "1:4: Cannot call [1]")
+}
+
+func TestClosures(t *testing.T) {
+	interp := NewInterpreter()
+
+	result, err := interp.Run(strings.Join([]string{
+		"counters = []",
+		"for name in [\"a\", \"b\"]",
+		"  local count = 0",
+		"  function next()",
+		"    count = count + 1",
+		"    return name + count",
+		"  end",
+		"  push(counters, next)",
+		"end",
+		"first = counters[0]",
+		"first()",
+		"[first(), counters[1]()]",
+	}, "\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, "[\"a2\", \"b1\"]", result.String())
+}
+
+func TestAnonymousFunctions(t *testing.T) {
+	interp := NewInterpreter()
+
+	result, err := interp.Run(strings.Join([]string{
+		"function makeCounter()",
+		"  local count = 0",
+		"  return fn() count = count + 1 end",
+		"end",
+		"function apply(f, x)",
+		"  return f(x)",
+		"end",
+		"counter = makeCounter()",
+		"counter()",
+		"fib = fn(n) if n < 2 then n else fib(n - 1) + fib(n - 2) end",
+		"add = fn(a) fn(b = 1) a + b end end",
+		"[counter(), apply(fn(n) n * n end, 7), fib(10), add(2)(3), add(2)(), fn(x) x end(4)]",
+	}, "\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, "[2, 49, 55, 5, 3, 4]", result.String())
+
+	_, err = interp.Run("f = fn(x) x")
+	assert.EqualError(t, err, "1:5: Expected end after fn")
+
+	_, err = interp.Run("f = fn(x) end")
+	assert.EqualError(t, err, "1:5: Expected expression in fn")
+}
+
+func TestNestedFunctions(t *testing.T) {
+	interp := NewInterpreter()
+
+	result, err := interp.Run(strings.Join([]string{
+		"function outer(n)",
+		"  local base = 10",
+		"  function isEven(x)",
+		"    if x == 0",
+		"      return true",
+		"    end",
+		"",
+		"    return isOdd(x - 1)",
+		"  end",
+		"",
+		"  function isOdd(x)",
+		"    if x == 0",
+		"      return false",
+		"    end",
+		"",
+		"    return isEven(x - 1)",
+		"  end",
+		"",
+		"  function addBase(x)",
+		"    return x + base + n",
+		"  end",
+		"",
+		"  return [isEven(n), addBase(1), addBase]",
+		"end",
+		"result = outer(3)",
+		"[result[0], result[1], result[2](2)]",
+	}, "\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, "[false, 14, 15]", result.String())
+
+	// Nested functions can't be called outside
+	// of the function they are declared in
+	_, err = interp.Run("isEven(2)")
+	assert.EqualError(t, err, "1:1: Function isEven not found")
+
+	_, err = interp.Run("function a()\n  function b()\n  return 1\nend")
+	assert.EqualError(t, err, "1: Missing end for function a")
+}
+
+func TestNamedArguments(t *testing.T) {
+	interp := NewInterpreter()
+
+	_, err := interp.Run(strings.Join([]string{
+		"function fib(index = 5, acc = 1, prev = 0)",
+		"  if index == 0",
+		"    return prev",
+		"  end",
+		"",
+		"  return fib(index - 1, acc + prev, acc)",
+		"end",
+	}, "\n"))
+	assert.Nil(t, err)
+
+	tests := []struct {
+		code   string
+		result string
+	}{
+		{"fib()", "5"},
+		{"fib(prev = 2)", "11"},
+		{"fib(6, prev = 1, acc = 1)", "13"},
+		{"fib(acc = 2 + 1, index = 1)", "3"},
+		{"f = fn(a, b = 2) a - b end\nf(b = 1, a = 5)", "4"},
+	}
+
+	for _, test := range tests {
+		result, err := interp.Run(test.code)
+		assert.Nil(t, err)
+		assert.Equal(t, test.result, result.String(), test.code)
+	}
+
+	errTests := []struct {
+		code string
+		err  string
+	}{
+		{"fib(last = 1)", "1:5: Unknown argument last in call to fib"},
+		{"fib(1, index = 2)", "1:8: Duplicate argument index in call to fib"},
+		{"fib(prev = 1, prev = 2)", "1:15: Duplicate argument prev in call to fib"},
+		{"fib(prev = 1, 2)", "1:1: Positional argument 2 after named arguments in call to fib"},
+		{"println(x = 1)", "1:9: Builtin functions do not take named arguments, got x = 1"},
+	}
+
+	for _, test := range errTests {
+		_, err := interp.Run(test.code)
+		assert.EqualError(t, err, test.err, test.code)
+	}
+
+	// A named argument is not
+	// assigned to a variable
+	_, err = interp.Run("prev")
+	assert.EqualError(t, err, "1:1: Variable prev not found")
+}
+
+func TestArityAndVariadics(t *testing.T) {
+	interp := NewInterpreter()
+
+	_, err := interp.Run(strings.Join([]string{
+		"function add(a, b)",
+		"  return a + b",
+		"end",
+		"",
+		"function greet(name, greeting = \"Hello\")",
+		"  return greeting + \" \" + name",
+		"end",
+		"",
+		"function sum(first, ...rest)",
+		"  total = first",
+		"  for n in rest",
+		"    total = total + n",
+		"  end",
+		"",
+		"  return total",
+		"end",
+	}, "\n"))
+	assert.Nil(t, err)
+
+	tests := []struct {
+		code   string
+		result string
+	}{
+		{"add(1, 2)", "3"},
+		{"greet(\"Ada\")", "\"Hello Ada\""},
+		{"greet(greeting = \"Hi\", name = \"Bo\")", "\"Hi Bo\""},
+		{"sum(1)", "1"},
+		{"sum(1, 2, 3, 4)", "10"},
+		{"fn(...xs) xs end(1, 2)", "[1, 2]"},
+		{"fn(...xs) xs end()", "[]"},
+	}
+
+	for _, test := range tests {
+		result, err := interp.Run(test.code)
+		assert.Nil(t, err)
+		assert.Equal(t, test.result, result.String(), test.code)
+	}
+
+	errTests := []struct {
+		code string
+		err  string
+	}{
+		{"add(1)", "1:1: add expects 2 arguments, got 1"},
+		{"add(1, 2, 3)", "1:1: add expects 2 arguments, got 3"},
+		{"greet()", "1:1: greet expects 1 to 2 arguments, got 0"},
+		{"greet(greeting = \"Hi\")", "1:1: Missing argument name in call to greet"},
+		{"greet(\"Ada\", \"Hi\", greeting = \"Yo\")", "1:20: Duplicate argument greeting in call to greet"},
+		{"add(1, 2, b = 3)", "1:11: Duplicate argument b in call to add"},
+		{"sum()", "1:1: sum expects at least 1 argument, got 0"},
+		{"sum(1, rest = 2)", "1:8: Cannot pass variadic argument rest by name in call to sum"},
+		{"function f(...a, b)\nend", "1: Variadic parameter a must be last"},
+		{"function f(...a = 1)\nend", "1: Variadic parameter a cannot have a default value"},
+	}
+
+	for _, test := range errTests {
+		_, err := interp.Run(test.code)
+		assert.EqualError(t, err, test.err, test.code)
+	}
+}
+
+func TestDefaultValues(t *testing.T) {
+	interp := NewInterpreter()
+
+	_, err := interp.Run(strings.Join([]string{
+		"function append_one(xs = [])",
+		"  push(xs, 1)",
+		"  return xs",
+		"end",
+		"function scale(n, by = n * 2, unit = \"x\" + \"s\")",
+		"  return [n * by, unit]",
+		"end",
+	}, "\n"))
+	assert.Nil(t, err)
+
+	tests := []struct {
+		code   string
+		result string
+	}{
+		{"append_one()", "[1]"},
+		{"append_one()", "[1]"},
+		{"append_one([5])", "[5, 1]"},
+		{"scale(3)", "[18, \"xs\"]"},
+		{"scale(3, 1)", "[3, \"xs\"]"},
+	}
+
+	for _, test := range tests {
+		result, err := interp.Run(test.code)
+		assert.Nil(t, err)
+		assert.Equal(t, test.result, result.String(), test.code)
+	}
+
+	// A default can call back into its own function
+	result, err := interp.Run(strings.Join([]string{
+		"function f(n, d = g(n) * 2)",
+		"  return d",
+		"end",
+		"function g(n)",
+		"  if n > 0",
+		"    return f(n - 1) + 1",
+		"  end",
+		"  return 0",
+		"end",
+		"f(1)",
+	}, "\n"))
+	assert.Nil(t, err)
+	assertFloat64FromNode(t, 2, result)
+
+	_, err = interp.Run("function f(x = missing)\n  return x\nend\nf()")
+	assert.EqualError(t, err, "1:16: Variable missing not found")
+}
+
+func TestMultipleReturnValues(t *testing.T) {
+	interp := NewInterpreter()
+
+	_, err := interp.Run(strings.Join([]string{
+		"function divmod(a, b)",
+		"  return (a - a % b) / b, a % b",
+		"end",
+	}, "\n"))
+	assert.Nil(t, err)
+
+	tests := []struct {
+		code   string
+		result string
+	}{
+		{"divmod(7, 2)", "[3, 1]"},
+		{"q, r = divmod(7, 2)\n[q, r]", "[3, 1]"},
+		{"a = 1\nb = 2\na, b = b, a\n[a, b]", "[2, 1]"},
+		{"xs = [1, 2]\nxs[0], xs[1] = xs[1], xs[0]\nxs", "[2, 1]"},
+		{"x, y, z = [1, 2, 3]\nx + y + z", "6"},
+		{"1, 2 + 3", "[1, 5]"},
+	}
+
+	for _, test := range tests {
+		result, err := interp.Run(test.code)
+		assert.Nil(t, err)
+		assert.Equal(t, test.result, result.String(), test.code)
+	}
+
+	_, err = interp.Run("a, b = 1, 2, 3")
+	assert.EqualError(t, err, "1:6: Cannot unpack 3 values into 2 targets")
+
+	_, err = interp.Run("a, b = 1")
+	assert.EqualError(t, err, "1:6: Cannot unpack 1 into a, b")
 }
@@ -0,0 +1,179 @@
+package blast
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Interpreter runs blast code with its own
+// scope stack, function table and builtins,
+// so many programs can run side by side
+type Interpreter struct {
+	mu       sync.Mutex
+	scopes   ScopeStack
+	frames   []Frame
+	builtins map[string]Function
+	out      io.Writer
+}
+
+// NewInterpreter returns a new Interpreter
+// with the builtin functions loaded
+func NewInterpreter() *Interpreter {
+	interp := new(Interpreter)
+	interp.scopes.scopes = []*Scope{NewScope()}
+	interp.scopes.size = 1
+	interp.builtins = make(map[string]Function)
+	interp.out = os.Stdout
+	interp.LoadBuiltinFunctions()
+	return interp
+}
+
+// SetOutput sets the writer that print
+// and println write to
+func (interp *Interpreter) SetOutput(w io.Writer) {
+	interp.mu.Lock()
+	defer interp.mu.Unlock()
+	interp.out = w
+}
+
+// Run parses and runs `code`, returning the
+// value of the last line that was run
+func (interp *Interpreter) Run(code string) (Node, error) {
+	return interp.runFileCode("", code)
+}
+
+// runFileCode parses and runs `code` read from the
+// file `fName`, returning the value of the last
+// line that was run
+func (interp *Interpreter) runFileCode(fName string, code string) (Node, error) {
+	interp.mu.Lock()
+	defer interp.mu.Unlock()
+
+	block, err := ParseFileCode(fName, code)
+	if err != nil {
+		return nil, err
+	}
+
+	defer interp.scopes.truncate(interp.scopes.size)
+	defer interp.truncateFrames(len(interp.frames))
+	node, _, err := block.RunBlocks(interp)
+	if err != nil {
+		return nil, err
+	}
+
+	if node == nil {
+		return &nodeNil{}, nil
+	}
+
+	return node, nil
+}
+
+// RunFile runs the blast code in the file `fName`
+func (interp *Interpreter) RunFile(fName string) error {
+	if !strings.HasSuffix(fName, ".blast") {
+		fName += ".blast"
+	}
+
+	data, err := ioutil.ReadFile(fName)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = interp.runFileCode(fName, string(data))
+	return err
+}
+
+// Call calls the function `name` with `args`, which may
+// be Nodes or float64, int, string, bool, []interface{}
+// or map[string]interface{} values
+func (interp *Interpreter) Call(name string, args ...interface{}) (Node, error) {
+	interp.mu.Lock()
+	defer interp.mu.Unlock()
+
+	f, err := interp.LookupFunc(name)
+	if err != nil {
+		return nil, err
+	}
+
+	ns := NewNodeStream()
+	for _, arg := range args {
+		node, err := NewNodeFromValue(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		ns.Push(node)
+	}
+
+	defer interp.scopes.truncate(interp.scopes.size)
+	defer interp.truncateFrames(len(interp.frames))
+
+	if err := interp.pushFrame(name, Pos{}, ns); err != nil {
+		return nil, err
+	}
+
+	result, err := f.Call(interp, ns)
+	if err != nil {
+		return nil, interp.traceError(err)
+	}
+
+	return result, nil
+}
+
+// NewNodeFromValue returns a Node
+// from a Go value
+func NewNodeFromValue(value interface{}) (Node, error) {
+	switch v := value.(type) {
+	case nil:
+		return &nodeNil{}, nil
+	case Node:
+		return v, nil
+	case float64:
+		return NewNumberFromFloat(v), nil
+	case int:
+		return NewNumberFromFloat(float64(v)), nil
+	case string:
+		return NewString(v), nil
+	case bool:
+		return NewBooleanFromBool(v), nil
+	case []interface{}:
+		values := make([]Node, len(v))
+		for i, item := range v {
+			node, err := NewNodeFromValue(item)
+			if err != nil {
+				return nil, err
+			}
+
+			values[i] = node
+		}
+
+		return NewList(values), nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+
+		// Go maps are unordered, so the
+		// keys are set in sorted order
+		sort.Strings(keys)
+		m := NewMap()
+		for _, key := range keys {
+			node, err := NewNodeFromValue(v[key])
+			if err != nil {
+				return nil, err
+			}
+
+			m.Set(NewString(key), node)
+		}
+
+		return m, nil
+	}
+
+	return nil, newRuntimeError("Cannot use %v (%T) as a value", value, value)
+}
@@ -0,0 +1,100 @@
+package blast
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterpreterRun(t *testing.T) {
+	interp := NewInterpreter()
+
+	result, err := interp.Run("x = 20\ny = x * 2")
+	assert.Nil(t, err)
+	assertFloat64FromNode(t, 40, result)
+
+	// Variables and functions are kept between runs
+	result, err = interp.Run("function double(n)\n  return n * 2\nend\ndouble(y)")
+	assert.Nil(t, err)
+	assertFloat64FromNode(t, 80, result)
+
+	// Functions can be called before they are declared
+	result, err = interp.Run("triple(x)\nfunction triple(n)\n  return n * 3\nend")
+	assert.Nil(t, err)
+	assertFloat64FromNode(t, 60, result)
+}
+
+func TestInterpreterCall(t *testing.T) {
+	interp := NewInterpreter()
+
+	_, err := interp.Run("function fib(index = 5, acc = 1, prev = 0)\n" +
+		"  if index == 1\n    return acc\n  end\n\n" +
+		"  return fib(index - 1, acc + prev, acc)\nend")
+	assert.Nil(t, err)
+
+	result, err := interp.Call("fib")
+	assert.Nil(t, err)
+	assertFloat64FromNode(t, 5, result)
+
+	result, err = interp.Call("fib", 10)
+	assert.Nil(t, err)
+	assertFloat64FromNode(t, 55, result)
+
+	_, err = interp.Call("fob")
+	assert.IsType(t, &ErrFuncNotFound{}, err)
+
+	_, err = interp.Call("fib", []int{})
+	assert.IsType(t, &ErrRuntime{}, err)
+
+	_, err = interp.Run("function describe(xs, m)\n  return [len(xs), keys(m)]\nend")
+	assert.Nil(t, err)
+
+	result, err = interp.Call("describe", []interface{}{1, "two"}, map[string]interface{}{"b": 1, "a": 2})
+	assert.Nil(t, err)
+	assert.Equal(t, "[2, [\"a\", \"b\"]]", result.String())
+}
+
+func TestInterpreterIsolation(t *testing.T) {
+	interp1, interp2 := NewInterpreter(), NewInterpreter()
+
+	_, err := interp1.Run("x = 1")
+	assert.Nil(t, err)
+
+	_, err = interp2.Run("x")
+	assert.IsType(t, &ErrRuntime{}, err)
+
+	// A failed run does not leave
+	// the Interpreter unusable
+	_, err = interp1.Run("if x == 1\n  y = z\nend")
+	assert.IsType(t, &ErrRuntime{}, err)
+
+	result, err := interp1.Run("x + 1")
+	assert.Nil(t, err)
+	assertFloat64FromNode(t, 2, result)
+	assert.Equal(t, 1, interp1.scopes.size)
+}
+
+func TestInterpreterConcurrency(t *testing.T) {
+	var wg sync.WaitGroup
+	outputs := make([]bytes.Buffer, 8)
+
+	for i := range outputs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			interp := NewInterpreter()
+			interp.SetOutput(&outputs[i])
+			_, err := interp.Run(fmt.Sprintf("n = %d\nfor 1 -> 100, i\n  n = n + 1\nend\nprint(n)", i))
+			assert.Nil(t, err)
+		}(i)
+	}
+
+	wg.Wait()
+
+	for i := range outputs {
+		assert.Equal(t, fmt.Sprintf("%d", i+100), outputs[i].String())
+	}
+}
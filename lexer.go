@@ -2,7 +2,6 @@ package blast
 
 import (
 	"fmt"
-	"log"
 	"strconv"
 	"strings"
 	"unicode"
@@ -20,12 +19,16 @@ func Lex(input string) *Lexer {
 // for lexical analysis.
 type Lexer struct {
 	pos        int
+	start      int
 	width      int
 	parenDepth int
 	tokenPos   int
+	line       int
+	file       string
 	text       string
 	curr       string
 	tokens     []*Token
+	err        error
 }
 
 // lexerFn is a recursive func type
@@ -35,13 +38,14 @@ type lexerFn func(l *Lexer) lexerFn
 // Item is lexed from a string,
 // precursor to a token.
 type Token struct {
-	typ  itemType
-	pos  int
-	text string
+	typ   itemType
+	pos   Pos
+	text  string
+	parts []string
 }
 
 // NewItem returns a new Item
-func NewToken(text string, pos int, typ itemType) *Token {
+func NewToken(text string, pos Pos, typ itemType) *Token {
 	item := new(Token)
 	item.pos = pos
 	item.text = text
@@ -75,6 +79,21 @@ const (
 	tokenTypeReturn
 	itemTypeEnd
 	tokenTypeEnd
+	tokenTypeElseIf
+	tokenTypeWhile
+	tokenTypeBreak
+	tokenTypeContinue
+	tokenTypeThen
+	tokenTypeOpenBracket
+	tokenTypeCloseBracket
+	tokenTypeOpenBrace
+	tokenTypeCloseBrace
+	tokenTypeColon
+	tokenTypeIn
+	tokenTypeLocal
+	tokenTypeFn
+	tokenTypeEllipsis
+	tokenTypeTemplate
 )
 
 // String returns a string representation
@@ -95,8 +114,22 @@ func (typ itemType) String() string {
 		return "Open paren"
 	case tokenTypeCloseParen:
 		return "Close paren"
+	case tokenTypeOpenBracket:
+		return "Open bracket"
+	case tokenTypeCloseBracket:
+		return "Close bracket"
+	case tokenTypeOpenBrace:
+		return "Open brace"
+	case tokenTypeCloseBrace:
+		return "Close brace"
+	case tokenTypeColon:
+		return "Colon"
 	case tokenTypeIdentifier:
 		return "Identifier"
+	case tokenTypeEllipsis:
+		return "Ellipsis"
+	case tokenTypeTemplate:
+		return "Template"
 	}
 
 	return "Unknown"
@@ -108,7 +141,6 @@ var (
 	// behavior occurs.
 	itemEOF = &Token{
 		typ:  tokenTypeTypeEOF,
-		pos:  -1,
 		text: "<NULL>",
 	}
 
@@ -118,18 +150,27 @@ var (
 	reservedKey = map[string]itemType{
 		"if":       tokenTypeIf,
 		"else":     tokenTypeElse,
+		"elseif":   tokenTypeElseIf,
 		"true":     tokenTypeBool,
 		"false":    tokenTypeBool,
 		"return":   tokenTypeReturn,
 		"function": tokenTypeFunction,
 		"end":      itemTypeEnd,
 		"for":      tokenTypeEnd,
+		"while":    tokenTypeWhile,
+		"break":    tokenTypeBreak,
+		"continue": tokenTypeContinue,
+		"then":     tokenTypeThen,
+		"in":       tokenTypeIn,
+		"local":    tokenTypeLocal,
+		"fn":       tokenTypeFn,
+		"not":      tokenTypeOperator,
 	}
 )
 
 // eof is returned from Next()
 // when there are no more characters
-const eof = -1
+const eof rune = -1
 
 // NewLexer returns a new Lexer
 // to lex the string `text`
@@ -160,18 +201,19 @@ func (l *Lexer) Lex() lexerFn {
 	if l.HasNext() {
 		r := l.Peek()
 
-		// Lex string literal
-		if r == '"' {
-			return l.LexString()
-		}
-
 		// Skip whitespace
 		if unicode.IsSpace(r) {
 			l.Next()
 			return l.Lex()
 		}
 
+		// Everything else starts a new token
+		l.start = l.pos
+
 		switch r {
+		// Lex string literal
+		case '"':
+			return l.LexString()
 		// Lex open paren
 		case '(':
 			l.Consume(l.Next())
@@ -182,11 +224,47 @@ func (l *Lexer) Lex() lexerFn {
 			l.Consume(l.Next())
 			l.PushItem(tokenTypeCloseParen)
 			return l.Lex()
+		// Lex open bracket
+		case '[':
+			l.Consume(l.Next())
+			l.PushItem(tokenTypeOpenBracket)
+			return l.Lex()
+		// Lex close bracket
+		case ']':
+			l.Consume(l.Next())
+			l.PushItem(tokenTypeCloseBracket)
+			return l.Lex()
+		// Lex open brace
+		case '{':
+			l.Consume(l.Next())
+			l.PushItem(tokenTypeOpenBrace)
+			return l.Lex()
+		// Lex close brace
+		case '}':
+			l.Consume(l.Next())
+			l.PushItem(tokenTypeCloseBrace)
+			return l.Lex()
+		// Lex colon
+		case ':':
+			l.Consume(l.Next())
+			l.PushItem(tokenTypeColon)
+			return l.Lex()
 		// Lex comma
 		case ',':
 			l.Consume(l.Next())
 			l.PushItem(tokenTypeComma)
 			return l.Lex()
+		// Lex the ellipsis before a
+		// variadic parameter
+		case '.':
+			if strings.HasPrefix(l.text[l.pos:], "...") {
+				for i := 0; i < 3; i++ {
+					l.Consume(l.Next())
+				}
+
+				l.PushItem(tokenTypeEllipsis)
+				return l.Lex()
+			}
 		}
 
 		// Lex identifier
@@ -194,6 +272,12 @@ func (l *Lexer) Lex() lexerFn {
 			return l.LexIdentifier()
 		}
 
+		// A `-` after a value subtracts
+		// rather than starting a number
+		if r == '-' && l.afterValue() {
+			return l.LexOperator()
+		}
+
 		// Lex number (int or float)
 		if r == '.' || r == '-' || unicode.IsNumber(r) {
 			return l.LexNumber()
@@ -203,6 +287,8 @@ func (l *Lexer) Lex() lexerFn {
 		if isOperatorPiece(r) {
 			return l.LexOperator()
 		}
+
+		return l.Errorf("Unexpected character %q", r)
 	}
 
 	return l.Stop()
@@ -218,11 +304,11 @@ func (l *Lexer) LexIdentifier() lexerFn {
 		return isAlphaNumeric(r) || r == '.'
 	})
 
-	// If the identifier is successfully
-	// parsed into a float, we will lex
-	// a number instead.
-	_, err := strconv.ParseFloat(l.curr, 64)
-	if err == nil {
+	// If the identifier starts with a number
+	// or contains a `.`, we will lex a
+	// number instead.
+	first, _ := utf8.DecodeRuneInString(l.curr)
+	if unicode.IsNumber(first) || strings.ContainsRune(l.curr, '.') {
 		return l.LexNumber()
 	}
 
@@ -232,6 +318,7 @@ func (l *Lexer) LexIdentifier() lexerFn {
 
 // LexNumber lexes a number (float or int)
 func (l *Lexer) LexNumber() lexerFn {
+	tooManyPoints := false
 	l.ConsumeWhileValid(func(r rune) bool {
 		// Check that a negative sign
 		// only occurs at the beginning
@@ -243,10 +330,11 @@ func (l *Lexer) LexNumber() lexerFn {
 		// occurs in the string
 		if r == '.' {
 			if strings.ContainsRune(l.curr, '.') {
-				l.Errorf("Too many decimal points in %s", l.curr+string(r))
-			} else {
-				return true
+				tooManyPoints = true
+				return false
 			}
+
+			return true
 		}
 
 		// Check the rune is a number
@@ -262,33 +350,187 @@ func (l *Lexer) LexNumber() lexerFn {
 		return l.LexOperator()
 	}
 
+	if tooManyPoints {
+		return l.Errorf("Too many decimal points in %s", l.curr+".")
+	}
+
+	if _, err := strconv.ParseFloat(l.curr, 64); err != nil {
+		return l.Errorf("Invalid number %s", l.curr)
+	}
+
 	l.PushItem(tokenTypeNum)
 	return l.Lex()
 }
 
 // LexOperator lexes an operator
 func (l *Lexer) LexOperator() lexerFn {
+	// A `!` is an operator on its
+	// own unless it starts `!=`
+	if rest := l.text[l.pos:]; strings.HasPrefix(rest, "!") && !strings.HasPrefix(rest, "!=") {
+		l.Consume(l.Next())
+		l.PushItem(tokenTypeOperator)
+		return l.Lex()
+	}
+
+	// A `-` or `!` that cannot continue the operator
+	// before it starts a prefix operator, as in `x=-1`
 	l.ConsumeWhileValid(func(r rune) bool {
+		if (r == '-' || r == '!') && isOperator(l.curr) {
+			return isOperator(l.curr + string(r))
+		}
+
 		return isOperatorPiece(r)
 	})
 
 	if !isOperator(l.curr) {
-		l.Errorf("Invalid operator %s", l.curr)
+		return l.Errorf("Invalid operator %s", l.curr)
 	}
 
 	l.PushItem(tokenTypeOperator)
 	return l.Lex()
 }
 
-// LexString lexes a string literal.
+// LexString lexes a string literal, decoding its
+// escape sequences. A string with `${expr}` in it
+// is a template, whose parts alternate between
+// text and the code of each expression
 func (l *Lexer) LexString() lexerFn {
+	if strings.HasPrefix(l.text[l.pos:], `"""`) {
+		return l.LexLongString()
+	}
+
+	var parts []string
 	l.Next()
-	l.ConsumeWhileValid(func(r rune) bool {
-		return r != '"'
-	})
+	for {
+		switch r := l.Next(); r {
+		case eof, '\n':
+			return l.Errorf("Unterminated string")
+		case '"':
+			if parts == nil {
+				l.PushItem(tokenTypeString)
+				return l.Lex()
+			}
+
+			// The template's text is its code
+			parts = append(parts, l.curr)
+			l.curr = l.text[l.start:l.pos]
+			l.PushItem(tokenTypeTemplate)
+			l.tokens[len(l.tokens)-1].parts = parts
+			return l.Lex()
+		case '\\':
+			if !l.LexEscape() {
+				return l.Stop()
+			}
+		case '$':
+			if l.Peek() != '{' {
+				l.Consume(r)
+				break
+			}
+
+			l.Next()
+			end := interpolationEnd(l.text[l.pos:])
+			if end == -1 {
+				return l.Errorf("Missing } after ${ in string")
+			}
+
+			parts = append(parts, l.curr, l.text[l.pos:l.pos+end])
+			l.curr = ""
+			l.pos += end + 1
+		default:
+			l.Consume(r)
+		}
+	}
+}
+
+// interpolationEnd returns the index of the `}` that
+// closes the expression at the start of `text`, or
+// -1 if it isn't closed. Braces and strings in the
+// expression are skipped
+func interpolationEnd(text string) int {
+	depth := 0
+	inString := false
+
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case inString && c == '\\':
+			i++
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '{':
+			depth++
+		case c == '}':
+			if depth == 0 {
+				return i
+			}
+
+			depth--
+		}
+	}
+
+	return -1
+}
+
+// LexEscape decodes the escape sequence after a `\`
+// in a string literal, returning false if it is
+// not valid
+func (l *Lexer) LexEscape() bool {
+	switch r := l.Next(); r {
+	case 'n':
+		l.Consume('\n')
+	case 't':
+		l.Consume('\t')
+	case '"', '\\', '$':
+		l.Consume(r)
+	case 'u':
+		end := -1
+		if l.Next() == '{' {
+			end = strings.IndexRune(l.text[l.pos:], '}')
+		}
+
+		if end == -1 {
+			l.Errorf("Expected \\u{...} in string")
+			return false
+		}
+
+		hex := l.text[l.pos : l.pos+end]
+		code, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			l.Errorf("Invalid unicode escape \\u{%s}", hex)
+			return false
+		}
+
+		l.pos += end + 1
+		l.Consume(rune(code))
+	case eof:
+		l.Errorf("Unterminated string")
+		return false
+	default:
+		l.Errorf("Unknown escape sequence \\%c", r)
+		return false
+	}
+
+	return true
+}
+
+// LexLongString lexes a string in triple quotes,
+// which can span lines and is read as it is
+// written. A line break right after the
+// opening quotes is skipped
+func (l *Lexer) LexLongString() lexerFn {
+	l.pos += len(`"""`)
+	if strings.HasPrefix(l.text[l.pos:], "\n") {
+		l.pos++
+	}
+
+	end := strings.Index(l.text[l.pos:], `"""`)
+	if end == -1 {
+		return l.Errorf("Unterminated string")
+	}
 
+	l.curr = l.text[l.pos : l.pos+end]
+	l.pos += end + len(`"""`)
 	l.PushItem(tokenTypeString)
-	l.Next()
 	return l.Lex()
 }
 
@@ -323,14 +565,15 @@ func (l *Lexer) Stop() lexerFn {
 // PushItem adds an item to the lexer's
 // `Item` slice
 func (l *Lexer) PushItem(typ itemType) *Lexer {
-	item := NewToken(l.curr, l.pos, typ)
+	item := NewToken(l.curr, l.Pos(), typ)
 	l.tokens = append(l.tokens, item)
 	l.curr = ""
 	return l
 }
 
 // AtTerminator determines if the next
-// rune is whitespace, a paren, or comma.
+// rune is whitespace, a paren, a bracket,
+// a brace, a colon or comma.
 func (l *Lexer) AtTerminator() bool {
 	r := l.Peek()
 
@@ -339,7 +582,7 @@ func (l *Lexer) AtTerminator() bool {
 	}
 
 	switch r {
-	case eof, ',', '(', ')':
+	case eof, ',', '(', ')', '[', ']', '{', '}', ':':
 		return true
 	}
 
@@ -427,6 +670,18 @@ func (l *Lexer) FirstItem() *Token {
 	return i
 }
 
+// HasItemType determines if any item
+// has the type `typ`
+func (l *Lexer) HasItemType(typ itemType) bool {
+	for _, item := range l.tokens {
+		if item.typ == typ {
+			return true
+		}
+	}
+
+	return false
+}
+
 // BackupItem decrements the item position
 func (l *Lexer) BackupItem() *Lexer {
 	l.tokenPos--
@@ -439,9 +694,55 @@ func (l *Lexer) HasNextItem() bool {
 	return l.tokenPos < len(l.tokens)
 }
 
-// Errorf reports a lexing error
-func (l *Lexer) Errorf(errFmt string, args ...interface{}) {
-	log.Fatalf("Blast error at pos "+string(l.pos)+": "+errFmt, args...)
+// Errorf records a lexing error at the start
+// of the text being lexed and stops the
+// lexical analysis
+func (l *Lexer) Errorf(errFmt string, args ...interface{}) lexerFn {
+	if l.err == nil {
+		l.err = newLexError(l.Pos(), errFmt, args...)
+	}
+
+	return l.Stop()
+}
+
+// Pos returns the position of the
+// token currently being lexed
+func (l *Lexer) Pos() Pos {
+	// Multi-line strings can put
+	// the token on a later line
+	before := l.text[:l.start]
+	line := l.line + strings.Count(before, "\n")
+	if i := strings.LastIndex(before, "\n"); i != -1 {
+		before = before[i+1:]
+	}
+
+	return Pos{
+		File: l.file,
+		Line: line,
+		Col:  utf8.RuneCountInString(before) + 1,
+	}
+}
+
+// Err returns the error that stopped
+// the lexical analysis, if any
+func (l *Lexer) Err() error {
+	return l.err
+}
+
+// afterValue determines if the last token
+// lexed ends a value, like a number or `)`
+func (l *Lexer) afterValue() bool {
+	if len(l.tokens) == 0 {
+		return false
+	}
+
+	switch l.tokens[len(l.tokens)-1].typ {
+	case tokenTypeNum, tokenTypeBool, tokenTypeString, tokenTypeTemplate, tokenTypeIdentifier,
+		tokenTypeCloseParen, tokenTypeCloseBracket, tokenTypeCloseBrace:
+		return true
+	}
+
+	return false
 }
 
 // parseItemTypeFromString returns the reserved
@@ -459,7 +760,8 @@ func parseItemTypeFromString(text string) itemType {
 // of operator chars is a valid operator
 func isOperator(strOp string) bool {
 	switch strOp {
-	case "+", "-", "*", "/", "=", "==", "&&", "||", "^", "<", "<=", ">", ">=", "!=", "->", "%":
+	case "+", "-", "*", "/", "=", "==", "&&", "||", "^", "<", "<=", ">", ">=", "!=", "->", "%",
+		"+=", "-=", "*=", "/=", "%=", "^=", "!":
 		return true
 	}
 
@@ -470,7 +772,7 @@ func isOperator(strOp string) bool {
 // is a valid piece or an operator
 func isOperatorPiece(r rune) bool {
 	switch r {
-	case '+', '-', '/', '*', '=', '&', '|', '^', '<', '>', '%':
+	case '+', '-', '/', '*', '=', '&', '|', '^', '<', '>', '%', '!':
 		return true
 	}
 
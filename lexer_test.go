@@ -48,6 +48,28 @@ func TestLexerItemText(t *testing.T) {
 	assert.Equal(t, "-", lexer.NextItem().text)
 	assert.Equal(t, "-30", lexer.NextItem().text)
 
+	lexer = NewLexer("5-3 !done != not(x)")
+	lexer.Lex()
+	assert.Equal(t, "5", lexer.NextItem().text)
+	assert.Equal(t, "-", lexer.NextItem().text)
+	assert.Equal(t, "3", lexer.NextItem().text)
+	assert.Equal(t, "!", lexer.NextItem().text)
+	assert.Equal(t, "done", lexer.NextItem().text)
+	assert.Equal(t, "!=", lexer.NextItem().text)
+	assert.Equal(t, tokenTypeOperator, int(lexer.NextItem().typ))
+
+	lexer = NewLexer("x=-y*!z!=-1")
+	lexer.Lex()
+	assert.Equal(t, "x", lexer.NextItem().text)
+	assert.Equal(t, "=", lexer.NextItem().text)
+	assert.Equal(t, "-", lexer.NextItem().text)
+	assert.Equal(t, "y", lexer.NextItem().text)
+	assert.Equal(t, "*", lexer.NextItem().text)
+	assert.Equal(t, "!", lexer.NextItem().text)
+	assert.Equal(t, "z", lexer.NextItem().text)
+	assert.Equal(t, "!=", lexer.NextItem().text)
+	assert.Equal(t, "-1", lexer.NextItem().text)
+
 	lexer = NewLexer("314.9 + \"str()_=182ing\"")
 	lexer.Lex()
 	assert.Equal(t, "314.9", lexer.NextItem().text)
@@ -63,6 +85,17 @@ func TestLexerItemText(t *testing.T) {
 	assert.Equal(t, "+", lexer.NextItem().text)
 	assert.Equal(t, "100", lexer.NextItem().text)
 
+	lexer = NewLexer("xs[0]=[1]")
+	lexer.Lex()
+	assert.Equal(t, "xs", lexer.NextItem().text)
+	assert.Equal(t, "Open bracket", lexer.NextItem().typ.String())
+	assert.Equal(t, "0", lexer.NextItem().text)
+	assert.Equal(t, "Close bracket", lexer.NextItem().typ.String())
+	assert.Equal(t, "=", lexer.NextItem().text)
+	assert.Equal(t, "[", lexer.NextItem().text)
+	assert.Equal(t, "1", lexer.NextItem().text)
+	assert.Equal(t, "]", lexer.NextItem().text)
+
 	lexer = NewLexer("((100 + 13) * 78)")
 	lexer.Lex()
 	assert.Equal(t, "(", lexer.NextItem().text)
@@ -93,7 +126,7 @@ func TestLexerItemText(t *testing.T) {
 }
 
 func TestLexerItemType(t *testing.T) {
-	lexer := NewLexer("200 200.89 -.99 && xx || \"derp\" (),")
+	lexer := NewLexer("-.99 200 200.89 && xx || \"derp\" (),")
 	lexer.Lex()
 
 	assertItemType(t, tokenTypeNum, lexer.NextItem())
@@ -135,6 +168,19 @@ func TestReservedItems(t *testing.T) {
 	assertItemType(t, itemTypeEnd, lexer.NextItem())
 	assertItemType(t, tokenTypeFunction, lexer.NextItem())
 	assertItemType(t, tokenTypeIdentifier, lexer.NextItem())
+
+	lexer = NewLexer("function f(...rest, x = .5)")
+	lexer.Lex()
+
+	assertItemType(t, tokenTypeFunction, lexer.NextItem())
+	assertItemType(t, tokenTypeIdentifier, lexer.NextItem())
+	assertItemType(t, tokenTypeOpenParen, lexer.NextItem())
+	assertItemType(t, tokenTypeEllipsis, lexer.NextItem())
+	assertItemType(t, tokenTypeIdentifier, lexer.NextItem())
+	assertItemType(t, tokenTypeComma, lexer.NextItem())
+	assertItemType(t, tokenTypeIdentifier, lexer.NextItem())
+	assertItemType(t, tokenTypeOperator, lexer.NextItem())
+	assertItemType(t, tokenTypeNum, lexer.NextItem())
 }
 
 func assertItemType(t *testing.T, expected itemType, actualItem *Token) {
@@ -145,3 +191,71 @@ func assertItemType(t *testing.T, expected itemType, actualItem *Token) {
 			expected, actual, actualItem)
 	}
 }
+
+func TestLexerErrors(t *testing.T) {
+	lexer := Lex("x = 1.2.3")
+	assert.IsType(t, &ErrLex{}, lexer.Err())
+	assert.Equal(t, Pos{Line: 0, Col: 5}, lexer.Err().(*ErrLex).Position())
+
+	lexer = Lex("x = 1 @ 2")
+	assert.IsType(t, &ErrLex{}, lexer.Err())
+
+	lexer = Lex("x <> 2")
+	assert.IsType(t, &ErrLex{}, lexer.Err())
+}
+
+func TestStringEscapes(t *testing.T) {
+	lexer := Lex(`"a\nb\t\"c\" \\ \u{e9}\u{1F600}"`)
+	assert.Nil(t, lexer.Err())
+	assert.Equal(t, "a\nb\t\"c\" \\ \u00e9\U0001F600", lexer.NextItem().text)
+
+	errTests := []struct {
+		code string
+		err  string
+	}{
+		{`x = "abc`, "Unterminated string"},
+		{`x = "a\qb"`, "Unknown escape sequence \\q"},
+		{`x = "\u41"`, "Expected \\u{...} in string"},
+		{`x = "\u{zz}"`, "Invalid unicode escape \\u{zz}"},
+		{`x = "\u{D800}"`, "Invalid unicode escape \\u{D800}"},
+	}
+
+	for _, test := range errTests {
+		lexer := Lex(test.code)
+		assert.IsType(t, &ErrLex{}, lexer.Err())
+		assert.EqualError(t, lexer.Err(), test.err, test.code)
+		assert.Equal(t, Pos{Line: 0, Col: 5}, lexer.Err().(*ErrLex).Position(), test.code)
+	}
+
+	assert.Equal(t, `"say \"hi\"\n"`, NewString("say \"hi\"\n").String())
+}
+
+func TestTemplateStrings(t *testing.T) {
+	lexer := Lex(`"x is ${x}, \${y} ${f("}", {1: 2})[1]}!" + "${}"`)
+	assert.Nil(t, lexer.Err())
+
+	item := lexer.NextItem()
+	assert.Equal(t, "Template", item.typ.String())
+	assert.Equal(t, `"x is ${x}, \${y} ${f("}", {1: 2})[1]}!"`, item.text)
+	assert.Equal(t, []string{"x is ", "x", ", ${y} ", `f("}", {1: 2})[1]`, "!"}, item.parts)
+
+	lexer.NextItem()
+	assert.Equal(t, []string{"", "", ""}, lexer.NextItem().parts)
+
+	lexer = Lex(`"a ${b"`)
+	assert.EqualError(t, lexer.Err(), "Missing } after ${ in string")
+}
+
+func TestTokenPositions(t *testing.T) {
+	lexer := NewLexer("x = \"é\" + max(12.5, y)")
+	lexer.file, lexer.line = "program.blast", 4
+	lexer.Lex()
+
+	assert.Equal(t, Pos{"program.blast", 4, 1}, lexer.NextItem().pos)
+	assert.Equal(t, Pos{"program.blast", 4, 3}, lexer.NextItem().pos)
+	assert.Equal(t, Pos{"program.blast", 4, 5}, lexer.NextItem().pos)
+	assert.Equal(t, Pos{"program.blast", 4, 9}, lexer.NextItem().pos)
+	assert.Equal(t, Pos{"program.blast", 4, 11}, lexer.NextItem().pos)
+	assert.Equal(t, Pos{"program.blast", 4, 14}, lexer.NextItem().pos)
+	assert.Equal(t, Pos{"program.blast", 4, 15}, lexer.NextItem().pos)
+}
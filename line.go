@@ -1,19 +1,18 @@
 package blast
 
-import (
-	"log"
-	"strings"
-)
+import "strings"
 
 // LineReader is a struct that
 // assists with reading lines
 type LineReader struct {
-	strLines []string
-	lines    []*Line
-	size     int
-	pos      int
-	nLines   int
-	lineNum  int
+	file       string
+	strLines   []string
+	lineStarts []int
+	lines      []*Line
+	size       int
+	pos        int
+	nLines     int
+	lineNum    int
 }
 
 var (
@@ -31,6 +30,10 @@ var (
 		itemTypeEnd:       lineTypeEnd,
 		tokenTypeIf:       lineTypeIf,
 		tokenTypeElse:     lineTypeElse,
+		tokenTypeElseIf:   lineTypeElseIf,
+		tokenTypeWhile:    lineTypeWhile,
+		tokenTypeBreak:    lineTypeBreak,
+		tokenTypeContinue: lineTypeContinue,
 		tokenTypeEnd:      lineTypeFor,
 		tokenTypeFunction: lineTypeFunction,
 		tokenTypeReturn:   lineTypeReturn,
@@ -40,8 +43,10 @@ var (
 // Line is a struct that contains
 // a `Lexer` and lineType
 type Line struct {
-	lexer *Lexer
-	typ   lineType
+	lexer    *Lexer
+	typ      lineType
+	pos      Pos
+	function *UserFunction
 }
 
 // String returns a string representation of a `Line`
@@ -51,14 +56,39 @@ func (l *Line) String() string {
 
 // Run evaluates the `NodeStream`
 // produced by the `Lexer`
-func (l *Line) Run() Node {
-	return l.NodeStream().Evaluate()
+func (l *Line) Run(interp *Interpreter) (Node, error) {
+	ns, err := l.NodeStream()
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := ns.Evaluate(interp)
+	if err != nil {
+		return nil, errorAt(err, l.pos)
+	}
+
+	return node, nil
 }
 
 // NodeStream returns a NodeStream
 // from the `Lexer`
-func (l *Line) NodeStream() *NodeStream {
-	return NewNodeStreamFromLexer(l.lexer)
+func (l *Line) NodeStream() (*NodeStream, error) {
+	ns, err := NewNodeStreamFromLexer(l.lexer)
+	if err != nil {
+		return nil, errorAt(err, l.pos)
+	}
+
+	return ns, nil
+}
+
+// Err returns the error from lexing
+// the `Line`, if any
+func (l *Line) Err() error {
+	if l.lexer == nil {
+		return nil
+	}
+
+	return l.lexer.Err()
 }
 
 // lineType is a int
@@ -75,12 +105,24 @@ const (
 	lineTypeElse
 	lineTypeEOF
 	lineTypeBlank
+	lineTypeElseIf
+	lineTypeWhile
+	lineTypeBreak
+	lineTypeContinue
 )
 
+// NewFileLineReader returns a new `LineReader`
+// for code read from the file `fName`
+func NewFileLineReader(fName string, buffer string) *LineReader {
+	lr := NewLineReader(buffer)
+	lr.file = fName
+	return lr
+}
+
 // NewLineReader returns a new `LineReader`
 func NewLineReader(buffer string) *LineReader {
 	lr := new(LineReader)
-	lr.strLines = strings.Split(buffer, "\n")
+	lr.strLines, lr.lineStarts = splitLines(buffer)
 	lr.lineNum = 1
 	lr.size = 0
 	lr.nLines = len(lr.strLines)
@@ -88,10 +130,14 @@ func NewLineReader(buffer string) *LineReader {
 }
 
 // ReadLines turns the string slice into `Line` types
-func (lr *LineReader) ReadLines() *LineReader {
+func (lr *LineReader) ReadLines() (*LineReader, error) {
 	index := 0
 
 	for line := lr.next(); line.typ != lineTypeEOF; line = lr.next() {
+		if err := line.Err(); err != nil {
+			return nil, err
+		}
+
 		if line.typ != lineTypeBlank {
 			lr.lines = append(lr.lines, line)
 			lr.size++
@@ -99,24 +145,48 @@ func (lr *LineReader) ReadLines() *LineReader {
 		}
 
 		if line.typ == lineTypeFunction {
-			lr.getFunction(line)
+			if err := lr.getFunction(line); err != nil {
+				return nil, err
+			}
 		}
 	}
 
 	lr.pos = 0
-	return lr
+	return lr, nil
 }
 
 // getFunction read the lines in a function declaration block
-// separately from the other blocks and adds a new `UserFunction`
-// to the Scope
-func (lr *LineReader) getFunction(line *Line) {
+// separately from the other blocks and stores the new
+// `UserFunction` on the declaration `Line`
+func (lr *LineReader) getFunction(line *Line) error {
 	depth := 1
-	f := ParseUserFunction(line.NodeStream())
+	ns, err := line.NodeStream()
+	if err != nil {
+		return err
+	}
+
+	f, err := ParseUserFunction(ns)
+	if err != nil {
+		return errorAt(err, line.pos)
+	}
+
 	newReader := new(LineReader)
 
 	for line := lr.next(); line.typ != lineTypeEOF; line = lr.next() {
-		if line.typ == lineTypeIf || line.typ == lineTypeFunction || line.typ == lineTypeFor {
+		if err := line.Err(); err != nil {
+			return err
+		}
+
+		// A nested function reads the lines
+		// up to and including its own end
+		if line.typ == lineTypeFunction {
+			if err := lr.getFunction(line); err != nil {
+				return err
+			}
+		}
+
+		if line.typ == lineTypeIf || line.typ == lineTypeFor ||
+			line.typ == lineTypeWhile {
 			depth++
 		}
 
@@ -127,19 +197,20 @@ func (lr *LineReader) getFunction(line *Line) {
 			}
 		}
 
-		if line.typ == lineTypeFunction {
-			log.Fatal("Cannot have function in function")
-		}
-
 		newReader.lines = append(newReader.lines, line)
 		newReader.size++
 	}
 
-	f.block = NewBlockBuilder(newReader).Build()
+	if depth != 0 {
+		return errorAt(newParseError("Missing end for function %s", f.name), line.pos)
+	}
 
-	if scopeIsInitalized {
-		SetFunc(f.name, f)
+	if f.block, err = NewBlockBuilder(newReader).Build(); err != nil {
+		return err
 	}
+
+	line.function = f
+	return nil
 }
 
 // NextLine returns the next `Line` from
@@ -178,9 +249,12 @@ func (lr *LineReader) next() *Line {
 	}
 
 	line := new(Line)
+	line.pos = Pos{File: lr.file, Line: lr.lineStarts[lr.pos]}
 
 	if !shouldSkipLine(lr.strLines[lr.pos]) {
 		line.lexer = NewLexer(lr.strLines[lr.pos])
+		line.lexer.file = lr.file
+		line.lexer.line = line.pos.Line
 		line.lexer.Lex()
 
 		if typ, ok := tokenLineKey[line.lexer.FirstItem().typ]; ok {
@@ -188,6 +262,12 @@ func (lr *LineReader) next() *Line {
 		} else {
 			line.typ = lineTypeBasic
 		}
+
+		// A one line if is evaluated like any
+		// other expression rather than as a block
+		if line.typ == lineTypeIf && line.lexer.HasItemType(tokenTypeThen) {
+			line.typ = lineTypeBasic
+		}
 	} else {
 		line.typ = lineTypeBlank
 	}
@@ -197,6 +277,61 @@ func (lr *LineReader) next() *Line {
 	return line
 }
 
+// splitLines splits code into lines, keeping a
+// string in triple quotes on the line it starts
+// on. It also returns the line number that
+// each line starts at
+func splitLines(buffer string) ([]string, []int) {
+	var lines []string
+	var starts []int
+	physical := strings.Split(buffer, "\n")
+
+	for i := 0; i < len(physical); i++ {
+		line, start := physical[i], i+1
+		for inLongString(line) && i+1 < len(physical) {
+			i++
+			line += "\n" + physical[i]
+		}
+
+		lines = append(lines, line)
+		starts = append(starts, start)
+	}
+
+	return lines, starts
+}
+
+// inLongString determines if a line of code
+// ends inside a string in triple quotes
+func inLongString(line string) bool {
+	if shouldSkipLine(line) {
+		return false
+	}
+
+	inString, inLong := false, false
+	for i := 0; i < len(line); i++ {
+		switch {
+		case inLong:
+			if strings.HasPrefix(line[i:], `"""`) {
+				inLong = false
+				i += 2
+			}
+		case inString:
+			if line[i] == '\\' {
+				i++
+			} else if line[i] == '"' {
+				inString = false
+			}
+		case strings.HasPrefix(line[i:], `"""`):
+			inLong = true
+			i += 2
+		case line[i] == '"':
+			inString = true
+		}
+	}
+
+	return inLong
+}
+
 // shouldSkipLine determines if a line should be skipped
 // (not read by the lexer) if it begines with the
 // comment identifier or contains only whitespace
@@ -4,6 +4,8 @@ import (
 	"io/ioutil"
 	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 // func TestLineReader(t *testing.T) {
@@ -33,3 +35,34 @@ func readTestCode(t *testing.T, fName string) string {
 
 	return string(data)
 }
+
+func TestSplitLines(t *testing.T) {
+	lines, starts := splitLines(strings.Join([]string{
+		"x = \"\"\"",
+		"a \"quoted\" line",
+		"\"\"\" + \"\\\"\"\"\"",
+		"-- not a \"\"\" string",
+		"y = 1",
+	}, "\n"))
+
+	assert.Equal(t, []string{
+		"x = \"\"\"\na \"quoted\" line\n\"\"\" + \"\\\"\"\"\"",
+		"-- not a \"\"\" string",
+		"y = 1",
+	}, lines)
+	assert.Equal(t, []int{1, 4, 5}, starts)
+}
+
+func TestMultiLineStrings(t *testing.T) {
+	interp := NewInterpreter()
+
+	result, err := interp.Run("s = \"\"\"\nfirst \\n\n  \"second\" line\"\"\"\ns")
+	assert.Nil(t, err)
+	assert.Equal(t, "first \\n\n  \"second\" line", result.(*String).value)
+
+	_, err = interp.Run("s = \"\"\"\n\n\"\"\" + missing")
+	assert.EqualError(t, err, "3:7: Variable missing not found")
+
+	_, err = interp.Run("s = \"\"\"\nnever closed")
+	assert.EqualError(t, err, "1:5: Unterminated string")
+}
@@ -0,0 +1,235 @@
+package blast
+
+import (
+	"math"
+	"unicode/utf8"
+)
+
+// maxRangeLength is the longest
+// List that range can make
+const maxRangeLength = 1 << 24
+
+// List is a struct that stores a slice of
+// Nodes. Every variable holding a List
+// shares it, like a Go slice pointer
+type List struct {
+	nodePos
+	values []Node
+}
+
+// GetType returns nodeTypeList
+func (l *List) GetType() nodeType {
+	return nodeTypeList
+}
+
+// String returns the List as a literal
+func (l *List) String() string {
+	return collectionString(l, make(map[Node]bool))
+}
+
+// NewList returns a new List
+// holding `values`
+func NewList(values []Node) *List {
+	list := new(List)
+	list.values = values
+	return list
+}
+
+// ListFromNode returns a List from a Node
+func ListFromNode(node Node) (*List, error) {
+	if list, ok := node.(*List); ok {
+		return list, nil
+	}
+
+	return nil, newRuntimeError("Could not get list from %v", node)
+}
+
+// listIndex returns the index `key` as an
+// int if it is in range for the List
+func listIndex(l *List, key Node) (int, error) {
+	i, err := IntFromNode(key)
+	if err != nil {
+		return 0, err
+	}
+
+	if i < 0 || i >= len(l.values) {
+		return 0, newRuntimeError("Index %d out of range for list of length %d", i, len(l.values))
+	}
+
+	return i, nil
+}
+
+// listIsEqualTo determines if two Lists have
+// equal items in the same order. A pair already
+// in `seen` is being compared further up, so it
+// counts as equal
+func listIsEqualTo(l1 *List, l2 *List, seen map[[2]Node]bool) (bool, error) {
+	pair := [2]Node{l1, l2}
+	if l1 == l2 || seen[pair] {
+		return true, nil
+	}
+
+	if len(l1.values) != len(l2.values) {
+		return false, nil
+	}
+
+	seen[pair] = true
+	defer delete(seen, pair)
+
+	for i := range l1.values {
+		equal, err := nodeIsEqualTo(l1.values[i], l2.values[i], seen)
+		if err != nil || !equal {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// builtinLen returns the number of items in a
+// List or Map or characters in a String
+func builtinLen(interp *Interpreter, args *NodeStream) (interface{}, error) {
+	if err := checkArgCount("len", args, 1, 1); err != nil {
+		return nil, err
+	}
+
+	switch arg := args.nodes[0].(type) {
+	case *List:
+		return len(arg.values), nil
+	case *Map:
+		return len(arg.keys), nil
+	case *String:
+		return utf8.RuneCountInString(arg.value), nil
+	}
+
+	return nil, newRuntimeError("Cannot get the length of %v", args.nodes[0])
+}
+
+// builtinPush adds values to the end of
+// a List and returns the List
+func builtinPush(interp *Interpreter, args *NodeStream) (interface{}, error) {
+	if err := checkArgCount("push", args, 2, -1); err != nil {
+		return nil, err
+	}
+
+	list, err := ListFromNode(args.nodes[0])
+	if err != nil {
+		return nil, err
+	}
+
+	list.values = append(list.values, args.nodes[1:]...)
+	return list, nil
+}
+
+// builtinPop removes and returns
+// the last item of a List
+func builtinPop(interp *Interpreter, args *NodeStream) (interface{}, error) {
+	if err := checkArgCount("pop", args, 1, 1); err != nil {
+		return nil, err
+	}
+
+	list, err := ListFromNode(args.nodes[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if len(list.values) == 0 {
+		return nil, newRuntimeError("Cannot pop from an empty list")
+	}
+
+	last := list.values[len(list.values)-1]
+	list.values = list.values[:len(list.values)-1]
+	return last, nil
+}
+
+// builtinSlice returns a new List with the items
+// of a List from `start` up to but not including
+// `end`, which defaults to the length of the List
+func builtinSlice(interp *Interpreter, args *NodeStream) (interface{}, error) {
+	if err := checkArgCount("slice", args, 2, 3); err != nil {
+		return nil, err
+	}
+
+	list, err := ListFromNode(args.nodes[0])
+	if err != nil {
+		return nil, err
+	}
+
+	start, err := IntFromNode(args.nodes[1])
+	if err != nil {
+		return nil, err
+	}
+
+	end := len(list.values)
+	if args.Length() == 3 {
+		if end, err = IntFromNode(args.nodes[2]); err != nil {
+			return nil, err
+		}
+	}
+
+	if start < 0 || end > len(list.values) || start > end {
+		return nil, newRuntimeError("Slice [%d:%d] out of range for list of length %d", start, end, len(list.values))
+	}
+
+	values := make([]Node, end-start)
+	copy(values, list.values[start:end])
+	return NewList(values), nil
+}
+
+// builtinConcat returns a new List with
+// the items of each List in order
+func builtinConcat(interp *Interpreter, args *NodeStream) (interface{}, error) {
+	values := []Node{}
+
+	for _, arg := range args.nodes {
+		list, err := ListFromNode(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, list.values...)
+	}
+
+	return NewList(values), nil
+}
+
+// builtinRange returns a List of the numbers from
+// `start` up to but not including `end`, counting
+// by `step`. With one argument, it is the `end`
+// and `start` is zero
+func builtinRange(interp *Interpreter, args *NodeStream) (interface{}, error) {
+	if err := checkArgCount("range", args, 1, 3); err != nil {
+		return nil, err
+	}
+
+	bounds := []int{0, 0, 1}
+	offset := 0
+	if args.Length() == 1 {
+		offset = 1
+	}
+
+	for i, arg := range args.nodes {
+		bound, err := IntFromNode(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		bounds[i+offset] = bound
+	}
+
+	start, end, step := bounds[0], bounds[1], bounds[2]
+	if step == 0 {
+		return nil, newRuntimeError("range step cannot be zero")
+	}
+
+	if count := math.Ceil((float64(end) - float64(start)) / float64(step)); count > maxRangeLength {
+		return nil, newRuntimeError("range of %v numbers is too long", count)
+	}
+
+	values := []Node{}
+	for i := start; (step > 0 && i < end) || (step < 0 && i > end); i += step {
+		values = append(values, NewNumberFromFloat(float64(i)))
+	}
+
+	return NewList(values), nil
+}
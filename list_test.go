@@ -0,0 +1,121 @@
+package blast
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListLiterals(t *testing.T) {
+	interp := NewInterpreter()
+
+	result, err := interp.Run("[1, \"two\", [3, true], []]")
+	assert.Nil(t, err)
+	assert.Equal(t, "[1, \"two\", [3, true], []]", result.String())
+
+	result, err = interp.Run("[1, 2] == [1, 2]")
+	assert.Nil(t, err)
+	assertBooleanFromNode(t, true, result)
+
+	result, err = interp.Run("[1, 2] == [1, \"2\"]")
+	assert.Nil(t, err)
+	assertBooleanFromNode(t, false, result)
+
+	_, err = interp.Run("[1, 2")
+	assert.IsType(t, &ErrParse{}, err)
+}
+
+func TestListIndexing(t *testing.T) {
+	interp := NewInterpreter()
+
+	result, err := interp.Run("xs = [10, [20, 30]]\nxs[1][0] + xs[0]")
+	assert.Nil(t, err)
+	assertFloat64FromNode(t, 30, result)
+
+	// Lists are shared between variables
+	result, err = interp.Run("ys = xs\nys[1][1] = 5\nxs[1]")
+	assert.Nil(t, err)
+	assert.Equal(t, "[20, 5]", result.String())
+
+	result, err = interp.Run("\"héllo\"[1]")
+	assert.Nil(t, err)
+	assertStringFromNode(t, "é", result)
+
+	_, err = interp.Run("xs[2]")
+	assert.EqualError(t, err, "1:3: Index 2 out of range for list of length 2")
+
+	_, err = interp.Run("xs[0.5]")
+	assert.IsType(t, &ErrRuntime{}, err)
+
+	_, err = interp.Run("xs[1e300]")
+	assert.EqualError(t, err, "1:3: Expected a whole number, got 1e+300")
+
+	_, err = interp.Run("\"abc\"[0] = \"x\"")
+	assert.IsType(t, &ErrRuntime{}, err)
+}
+
+func TestListBuiltins(t *testing.T) {
+	interp := NewInterpreter()
+
+	result, err := interp.Run("xs = []\npush(xs, 1, 2, 3)\nlen(xs)")
+	assert.Nil(t, err)
+	assertFloat64FromNode(t, 3, result)
+
+	result, err = interp.Run("pop(xs)")
+	assert.Nil(t, err)
+	assertFloat64FromNode(t, 3, result)
+
+	result, err = interp.Run("concat(xs, slice([1, 2, 3, 4], 1, 3), slice(xs, 1))")
+	assert.Nil(t, err)
+	assert.Equal(t, "[1, 2, 2, 3, 2]", result.String())
+
+	result, err = interp.Run("len(\"héllo\")")
+	assert.Nil(t, err)
+	assertFloat64FromNode(t, 5, result)
+
+	_, err = interp.Run("pop([])")
+	assert.IsType(t, &ErrRuntime{}, err)
+
+	_, err = interp.Run("slice(xs, 2, 1)")
+	assert.IsType(t, &ErrRuntime{}, err)
+
+	_, err = interp.Run("len(xs, xs)")
+	assert.EqualError(t, err, "1:1: len expects 1 argument, got 2")
+
+	// A List inside itself is not printed forever
+	result, err = interp.Run("push(xs, xs)")
+	assert.Nil(t, err)
+	assert.Equal(t, "[1, 2, [...]]", result.String())
+
+	// Nor compared forever
+	result, err = interp.Run("a = [1]\nb = [1]\npush(a, a)\npush(b, b)\na == b")
+	assert.Nil(t, err)
+	assertBooleanFromNode(t, true, result)
+
+	result, err = interp.Run("c = [2]\npush(c, c)\na == c")
+	assert.Nil(t, err)
+	assertBooleanFromNode(t, false, result)
+}
+
+func TestRange(t *testing.T) {
+	interp := NewInterpreter()
+
+	result, err := interp.Run("[range(3), range(2, 5), range(5, 0, -2), range(0)]")
+	assert.Nil(t, err)
+	assert.Equal(t, "[[0, 1, 2], [2, 3, 4], [5, 3, 1], []]", result.String())
+
+	_, err = interp.Run("range(0, 5, 0)")
+	assert.IsType(t, &ErrRuntime{}, err)
+
+	_, err = interp.Run("range(1.5)")
+	assert.IsType(t, &ErrRuntime{}, err)
+
+	_, err = interp.Run("range(0, 1e300, 1e299)")
+	assert.IsType(t, &ErrRuntime{}, err)
+
+	_, err = interp.Run("range(0, 1e10)")
+	assert.EqualError(t, err, "1:1: range of 1e+10 numbers is too long")
+
+	_, err = interp.Run("range(1e10, 0, -1)")
+	assert.IsType(t, &ErrRuntime{}, err)
+}
@@ -0,0 +1,231 @@
+package blast
+
+import "math"
+
+// Map is a struct that stores values by key.
+// Keys are kept in the order they were first
+// set, so iterating a Map is deterministic
+type Map struct {
+	nodePos
+	keys   []Node
+	values map[mapKey]Node
+}
+
+// mapKey is the comparable form of a
+// Number, String or Boolean key
+type mapKey struct {
+	typ   nodeType
+	value string
+}
+
+// GetType returns nodeTypeMap
+func (m *Map) GetType() nodeType {
+	return nodeTypeMap
+}
+
+// String returns the Map as a literal
+func (m *Map) String() string {
+	return collectionString(m, make(map[Node]bool))
+}
+
+// NewMap returns a new empty Map
+func NewMap() *Map {
+	m := new(Map)
+	m.values = make(map[mapKey]Node)
+	return m
+}
+
+// MapFromNode returns a Map from a Node
+func MapFromNode(node Node) (*Map, error) {
+	if m, ok := node.(*Map); ok {
+		return m, nil
+	}
+
+	return nil, newRuntimeError("Could not get map from %v", node)
+}
+
+// newMapKey returns the mapKey of `key`
+func newMapKey(key Node) (mapKey, error) {
+	switch key.GetType() {
+	case nodeTypeNumber:
+		// NaN is not equal to itself, and -0
+		// is equal to 0, so it is the same key
+		num := key.(*Number).value
+		if math.IsNaN(num) {
+			return mapKey{}, newRuntimeError("Cannot use NaN as a map key")
+		}
+
+		if num == 0 {
+			num = 0
+		}
+
+		return mapKey{nodeTypeNumber, NewNumberFromFloat(num).String()}, nil
+	case nodeTypeString, nodeTypeBoolean:
+		return mapKey{key.GetType(), key.String()}, nil
+	}
+
+	return mapKey{}, newRuntimeError("Cannot use %v as a map key", key)
+}
+
+// Get returns the value stored at `key`
+func (m *Map) Get(key Node) (Node, error) {
+	k, err := newMapKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if value, ok := m.values[k]; ok {
+		return value, nil
+	}
+
+	return nil, newRuntimeError("Key %v not found", key)
+}
+
+// Set stores `value` at `key`
+func (m *Map) Set(key Node, value Node) error {
+	k, err := newMapKey(key)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := m.values[k]; !ok {
+		m.keys = append(m.keys, key)
+	}
+
+	m.values[k] = value
+	return nil
+}
+
+// Has determines if a value
+// is stored at `key`
+func (m *Map) Has(key Node) (bool, error) {
+	k, err := newMapKey(key)
+	if err != nil {
+		return false, err
+	}
+
+	_, ok := m.values[k]
+	return ok, nil
+}
+
+// Delete removes `key` and its value, and
+// determines if the key was in the Map
+func (m *Map) Delete(key Node) (bool, error) {
+	k, err := newMapKey(key)
+	if err != nil {
+		return false, err
+	}
+
+	if _, ok := m.values[k]; !ok {
+		return false, nil
+	}
+
+	delete(m.values, k)
+	for i, existing := range m.keys {
+		if existingKey, _ := newMapKey(existing); existingKey == k {
+			m.keys = append(m.keys[:i], m.keys[i+1:]...)
+			break
+		}
+	}
+
+	return true, nil
+}
+
+// mapIsEqualTo determines if two Maps have the
+// same keys with equal values, in any order. A
+// pair already in `seen` is being compared
+// further up, so it counts as equal
+func mapIsEqualTo(m1 *Map, m2 *Map, seen map[[2]Node]bool) (bool, error) {
+	pair := [2]Node{m1, m2}
+	if m1 == m2 || seen[pair] {
+		return true, nil
+	}
+
+	if len(m1.keys) != len(m2.keys) {
+		return false, nil
+	}
+
+	seen[pair] = true
+	defer delete(seen, pair)
+
+	for k, v1 := range m1.values {
+		v2, ok := m2.values[k]
+		if !ok {
+			return false, nil
+		}
+
+		equal, err := nodeIsEqualTo(v1, v2, seen)
+		if err != nil || !equal {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// builtinKeys returns a List of
+// the keys of a Map in order
+func builtinKeys(interp *Interpreter, args *NodeStream) (interface{}, error) {
+	if err := checkArgCount("keys", args, 1, 1); err != nil {
+		return nil, err
+	}
+
+	m, err := MapFromNode(args.nodes[0])
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]Node, len(m.keys))
+	copy(keys, m.keys)
+	return NewList(keys), nil
+}
+
+// builtinValues returns a List of the
+// values of a Map in the order of its keys
+func builtinValues(interp *Interpreter, args *NodeStream) (interface{}, error) {
+	if err := checkArgCount("values", args, 1, 1); err != nil {
+		return nil, err
+	}
+
+	m, err := MapFromNode(args.nodes[0])
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]Node, len(m.keys))
+	for i, key := range m.keys {
+		values[i], _ = m.Get(key)
+	}
+
+	return NewList(values), nil
+}
+
+// builtinHas determines if a Map
+// has a value stored at a key
+func builtinHas(interp *Interpreter, args *NodeStream) (interface{}, error) {
+	if err := checkArgCount("has", args, 2, 2); err != nil {
+		return nil, err
+	}
+
+	m, err := MapFromNode(args.nodes[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return m.Has(args.nodes[1])
+}
+
+// builtinDelete removes a key from a Map and
+// determines if the key was in the Map
+func builtinDelete(interp *Interpreter, args *NodeStream) (interface{}, error) {
+	if err := checkArgCount("delete", args, 2, 2); err != nil {
+		return nil, err
+	}
+
+	m, err := MapFromNode(args.nodes[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return m.Delete(args.nodes[1])
+}
@@ -0,0 +1,87 @@
+package blast
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapLiterals(t *testing.T) {
+	interp := NewInterpreter()
+
+	result, err := interp.Run("{\"b\": [1, 2], \"a\": {1: true}}")
+	assert.Nil(t, err)
+	assert.Equal(t, "{\"b\": [1, 2], \"a\": {1: true}}", result.String())
+
+	result, err = interp.Run("{\"a\": 1, \"b\": 2} == {\"b\": 2, \"a\": 1}")
+	assert.Nil(t, err)
+	assertBooleanFromNode(t, true, result)
+
+	result, err = interp.Run("{\"a\": 1} == {\"a\": \"1\"}")
+	assert.Nil(t, err)
+	assertBooleanFromNode(t, false, result)
+
+	_, err = interp.Run("{\"a\", 1}")
+	assert.IsType(t, &ErrParse{}, err)
+
+	_, err = interp.Run("{\"a\": 1: 2}")
+	assert.IsType(t, &ErrParse{}, err)
+
+	_, err = interp.Run("{\"a\"}")
+	assert.IsType(t, &ErrParse{}, err)
+}
+
+func TestMapIndexing(t *testing.T) {
+	interp := NewInterpreter()
+
+	result, err := interp.Run("m = {\"a\": 1}\nm[\"b\"] = 2\nm[\"a\"] = m[\"a\"] + m[\"b\"]\nm")
+	assert.Nil(t, err)
+	assert.Equal(t, "{\"a\": 3, \"b\": 2}", result.String())
+
+	// Keys of different types are different keys
+	result, err = interp.Run("n = {1: \"number\", \"1\": \"string\"}\nn[1]")
+	assert.Nil(t, err)
+	assertStringFromNode(t, "number", result)
+
+	_, err = interp.Run("m[\"c\"]")
+	assert.EqualError(t, err, "1:2: Key \"c\" not found")
+
+	_, err = interp.Run("m[[1]] = 1")
+	assert.IsType(t, &ErrRuntime{}, err)
+
+	// Equal numbers are the same key
+	result, err = interp.Run("z = {}\nz[0] = 1\nz[-0] += 1\nz[0.0]")
+	assert.Nil(t, err)
+	assertFloat64FromNode(t, 2, result)
+
+	_, err = interp.Run("z[sqrt(-1)] = 1")
+	assert.EqualError(t, err, "1:13: Cannot use NaN as a map key")
+
+	// Maps inside themselves are not compared forever
+	result, err = interp.Run("a = {}\nb = {}\na[\"self\"] = a\nb[\"self\"] = b\n[a == b, a]")
+	assert.Nil(t, err)
+	assert.Equal(t, "[true, {\"self\": {...}}]", result.String())
+}
+
+func TestMapBuiltins(t *testing.T) {
+	interp := NewInterpreter()
+
+	result, err := interp.Run("m = {\"c\": 1, \"a\": 2, \"b\": 3}\ndelete(m, \"a\")")
+	assert.Nil(t, err)
+	assertBooleanFromNode(t, true, result)
+
+	result, err = interp.Run("m[\"a\"] = 4\n[keys(m), values(m), len(m)]")
+	assert.Nil(t, err)
+	assert.Equal(t, "[[\"c\", \"b\", \"a\"], [1, 3, 4], 3]", result.String())
+
+	result, err = interp.Run("has(m, \"b\") && has(m, \"d\") == false")
+	assert.Nil(t, err)
+	assertBooleanFromNode(t, true, result)
+
+	result, err = interp.Run("delete(m, \"d\")")
+	assert.Nil(t, err)
+	assertBooleanFromNode(t, false, result)
+
+	_, err = interp.Run("keys([1])")
+	assert.IsType(t, &ErrRuntime{}, err)
+}
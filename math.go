@@ -0,0 +1,146 @@
+package blast
+
+import "math"
+
+// The math builtins that take one
+// number and return a number
+var (
+	builtinSqrt  = unaryMathFunc("sqrt", math.Sqrt)
+	builtinAbs   = unaryMathFunc("abs", math.Abs)
+	builtinFloor = unaryMathFunc("floor", math.Floor)
+	builtinCeil  = unaryMathFunc("ceil", math.Ceil)
+	builtinRound = unaryMathFunc("round", math.Round)
+	builtinSin   = unaryMathFunc("sin", math.Sin)
+	builtinCos   = unaryMathFunc("cos", math.Cos)
+	builtinTan   = unaryMathFunc("tan", math.Tan)
+	builtinExp   = unaryMathFunc("exp", math.Exp)
+)
+
+// The math builtins that
+// return a constant
+var (
+	builtinPi  = constantMathFunc("pi", math.Pi)
+	builtinE   = constantMathFunc("e", math.E)
+	builtinInf = constantMathFunc("inf", math.Inf(1))
+)
+
+// numberArg returns the value of a Number passed
+// to a builtin. Unlike Float64FromNode, Booleans
+// are not converted
+func numberArg(node Node) (float64, error) {
+	if num, ok := node.(*Number); ok {
+		return num.value, nil
+	}
+
+	return 0, newRuntimeError("Expected a number, got %v", node)
+}
+
+// numberArgs returns the values of the Numbers
+// passed to the builtin `name`, checking that
+// there are from `min` to `max` of them
+func numberArgs(name string, args *NodeStream, min int, max int) ([]float64, error) {
+	if err := checkArgCount(name, args, min, max); err != nil {
+		return nil, err
+	}
+
+	nums := make([]float64, args.Length())
+	for i, arg := range args.nodes {
+		num, err := numberArg(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		nums[i] = num
+	}
+
+	return nums, nil
+}
+
+// unaryMathFunc returns a goFunc for the builtin
+// `name`, which calls `f` with one number
+func unaryMathFunc(name string, f func(float64) float64) goFunc {
+	return func(interp *Interpreter, args *NodeStream) (interface{}, error) {
+		nums, err := numberArgs(name, args, 1, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		return f(nums[0]), nil
+	}
+}
+
+// constantMathFunc returns a goFunc for the
+// builtin `name`, which returns `value`
+func constantMathFunc(name string, value float64) goFunc {
+	return func(interp *Interpreter, args *NodeStream) (interface{}, error) {
+		if err := checkArgCount(name, args, 0, 0); err != nil {
+			return nil, err
+		}
+
+		return value, nil
+	}
+}
+
+// builtinAtan2 returns the arc tangent
+// of `y` / `x`, using their signs to
+// find the quadrant
+func builtinAtan2(interp *Interpreter, args *NodeStream) (interface{}, error) {
+	nums, err := numberArgs("atan2", args, 2, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	return math.Atan2(nums[0], nums[1]), nil
+}
+
+// builtinLog returns the natural logarithm
+// of a number, or its logarithm in `base`
+// if it is passed
+func builtinLog(interp *Interpreter, args *NodeStream) (interface{}, error) {
+	nums, err := numberArgs("log", args, 1, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nums) == 2 {
+		return math.Log(nums[0]) / math.Log(nums[1]), nil
+	}
+
+	return math.Log(nums[0]), nil
+}
+
+// builtinMin returns the smallest of its
+// arguments, or of the items of a List
+func builtinMin(interp *Interpreter, args *NodeStream) (interface{}, error) {
+	return extremum("min", args, math.Min)
+}
+
+// builtinMax returns the largest of its
+// arguments, or of the items of a List
+func builtinMax(interp *Interpreter, args *NodeStream) (interface{}, error) {
+	return extremum("max", args, math.Max)
+}
+
+// extremum returns the number that `pick` chooses
+// out of the arguments to the builtin `name`, or
+// the items of a List if it is the only one
+func extremum(name string, args *NodeStream, pick func(float64, float64) float64) (interface{}, error) {
+	if list, ok := args.Top().(*List); ok && args.Length() == 1 {
+		args = NewNodeStream()
+		for _, value := range list.values {
+			args.Push(value)
+		}
+	}
+
+	nums, err := numberArgs(name, args, 1, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	result := nums[0]
+	for _, num := range nums[1:] {
+		result = pick(result, num)
+	}
+
+	return result, nil
+}
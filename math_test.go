@@ -0,0 +1,50 @@
+package blast
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMathBuiltins(t *testing.T) {
+	interp := NewInterpreter()
+
+	tests := []struct {
+		code   string
+		result string
+	}{
+		{"sqrt(16)", "4"},
+		{"[abs(-2.5), floor(-1.5), ceil(1.2), round(2.5), round(-2.5)]", "[2.5, -2, 2, 3, -3]"},
+		{"[min(3, 1, 2), max(3, 1, 2), max([4, 9, 2]), min(5)]", "[1, 3, 9, 5]"},
+		{"[sin(0), cos(0), tan(0), atan2(0, -1) == pi()]", "[0, 1, 0, true]"},
+		{"[log(e()), log(8, 2), exp(0)]", "[1, 3, 1]"},
+		{"[inf(), -inf(), 1 / inf()]", "[+Inf, -Inf, 0]"},
+		{"floor(pi() * 100) / 100", "3.14"},
+		{"sqrt(-1) == sqrt(-1)", "false"},
+	}
+
+	for _, test := range tests {
+		result, err := interp.Run(test.code)
+		assert.Nil(t, err, test.code)
+		assert.Equal(t, test.result, result.String(), test.code)
+	}
+
+	errTests := []struct {
+		code string
+		err  string
+	}{
+		{"sqrt()", "1:1: sqrt expects 1 argument, got 0"},
+		{"sqrt(\"4\")", "1:1: Expected a number, got \"4\""},
+		{"abs(true)", "1:1: Expected a number, got true"},
+		{"pi(1)", "1:1: pi expects 0 arguments, got 1"},
+		{"atan2(1)", "1:1: atan2 expects 2 arguments, got 1"},
+		{"log(1, 2, 3)", "1:1: log expects 1 to 2 arguments, got 3"},
+		{"max()", "1:1: max expects at least 1 argument, got 0"},
+		{"min([1, \"a\"])", "1:1: Expected a number, got \"a\""},
+	}
+
+	for _, test := range errTests {
+		_, err := interp.Run(test.code)
+		assert.EqualError(t, err, test.err, test.code)
+	}
+}
@@ -2,8 +2,9 @@ package blast
 
 import (
 	"fmt"
-	"log"
+	"math"
 	"strconv"
+	"strings"
 )
 
 // Node is an interface
@@ -12,6 +13,30 @@ import (
 type Node interface {
 	GetType() nodeType
 	String() string
+	Pos() Pos
+}
+
+// nodePos stores the position a Node
+// was lexed from, and is embedded
+// in every Node type
+type nodePos struct {
+	pos Pos
+}
+
+// Pos returns the position of the Node
+func (n *nodePos) Pos() Pos {
+	return n.pos
+}
+
+// setPos sets the position of the Node
+func (n *nodePos) setPos(pos Pos) {
+	n.pos = pos
+}
+
+// posSetter is implemented by Nodes
+// that store their position
+type posSetter interface {
+	setPos(pos Pos)
 }
 
 // nodeType is an int
@@ -20,7 +45,7 @@ type Node interface {
 type nodeType int
 
 const (
-	nodeTypeUnkown = iota
+	nodeTypeUnkown nodeType = iota
 	nodeTypeFuncCall
 	nodeTypeVariable
 	nodeTypeNumber
@@ -31,11 +56,25 @@ const (
 	nodeTypeComma
 	nodeTypeArgCount
 	nodeTypeReserved
+	nodeTypeOneLineIf
+	nodeTypeBracket
+	nodeTypeList
+	nodeTypeIndex
+	nodeTypeBrace
+	nodeTypeColon
+	nodeTypeMap
+	nodeTypeFunction
+	nodeTypeLambda
+	nodeTypeNamedArg
+	nodeTypeTuple
+	nodeTypeShortCircuit
+	nodeTypeTemplate
 )
 
 // Operator is a struct
 // that stores an opType
 type Operator struct {
+	nodePos
 	typ opType
 }
 
@@ -44,7 +83,7 @@ type Operator struct {
 type opType int
 
 const (
-	opTypeAddition = iota
+	opTypeAddition opType = iota
 	opTypeSubtraction
 	opTypeMultiplication
 	opTypeDivision
@@ -60,27 +99,44 @@ const (
 	opTypeOr
 	opTypeArrow
 	opTypeModulus
+	opTypeIndex
+	opTypeAddAssign
+	opTypeSubtractAssign
+	opTypeMultiplyAssign
+	opTypeDivideAssign
+	opTypeModulusAssign
+	opTypeExponentAssign
+	opTypeNegate
+	opTypeNot
 )
 
 // operatorKey is used to get an
 // operator type from a string
 var operatorKey = map[string]opType{
-	"+":  opTypeAddition,
-	"-":  opTypeSubtraction,
-	"*":  opTypeMultiplication,
-	"/":  opTypeDivision,
-	"^":  opTypeExponent,
-	"=":  opTypeAssignment,
-	"==": opTypeEqualTo,
-	"!=": opTypeNotEqualTo,
-	"<":  opTypeLessThan,
-	"<=": opTypeLessThanOrEqualTo,
-	">":  opTypeGreaterThan,
-	">=": opTypeGreaterThanOrEqualTo,
-	"&&": opTypeAnd,
-	"||": opTypeOr,
-	"->": opTypeArrow,
-	"%":  opTypeModulus,
+	"+":   opTypeAddition,
+	"-":   opTypeSubtraction,
+	"*":   opTypeMultiplication,
+	"/":   opTypeDivision,
+	"^":   opTypeExponent,
+	"=":   opTypeAssignment,
+	"==":  opTypeEqualTo,
+	"!=":  opTypeNotEqualTo,
+	"<":   opTypeLessThan,
+	"<=":  opTypeLessThanOrEqualTo,
+	">":   opTypeGreaterThan,
+	">=":  opTypeGreaterThanOrEqualTo,
+	"&&":  opTypeAnd,
+	"||":  opTypeOr,
+	"->":  opTypeArrow,
+	"%":   opTypeModulus,
+	"+=":  opTypeAddAssign,
+	"-=":  opTypeSubtractAssign,
+	"*=":  opTypeMultiplyAssign,
+	"/=":  opTypeDivideAssign,
+	"%=":  opTypeModulusAssign,
+	"^=":  opTypeExponentAssign,
+	"!":   opTypeNot,
+	"not": opTypeNot,
 }
 
 // operatorStrings is used to get a
@@ -103,6 +159,15 @@ var operatorStrings = map[opType]string{
 	opTypeOr:                   "||",
 	opTypeArrow:                "->",
 	opTypeModulus:              "%",
+	opTypeIndex:                "[]",
+	opTypeAddAssign:            "+=",
+	opTypeSubtractAssign:       "-=",
+	opTypeMultiplyAssign:       "*=",
+	opTypeDivideAssign:         "/=",
+	opTypeModulusAssign:        "%=",
+	opTypeExponentAssign:       "^=",
+	opTypeNegate:               "-",
+	opTypeNot:                  "!",
 }
 
 // GetType returns nodeTypeOperator
@@ -116,20 +181,21 @@ func (o *Operator) String() string {
 }
 
 // NewOperator returns a new Operator
-func NewOperator(strOp string) *Operator {
+func NewOperator(strOp string) (*Operator, error) {
 	operator := new(Operator)
 
 	if ot, ok := operatorKey[strOp]; ok {
 		operator.typ = ot
 	} else {
-		log.Fatalf("Could not parse operator %s", strOp)
+		return nil, newParseError("Could not parse operator %s", strOp)
 	}
 
-	return operator
+	return operator, nil
 }
 
 // Number is a struct that stores a float64
 type Number struct {
+	nodePos
 	value float64
 }
 
@@ -144,16 +210,16 @@ func (n *Number) String() string {
 }
 
 // NewNumber returns a new Number
-func NewNumber(strNum string) *Number {
+func NewNumber(strNum string) (*Number, error) {
 	number := new(Number)
 	value, err := strconv.ParseFloat(strNum, 64)
 
 	if err != nil {
-		log.Fatal(err.Error())
+		return nil, newParseError("Could not parse number %s", strNum)
 	}
 
 	number.value = value
-	return number
+	return number, nil
 }
 
 // NewNumberFromFloat returns a Number from a float64
@@ -166,6 +232,7 @@ func NewNumberFromFloat(value float64) *Number {
 // Boolean is a struct that
 // stores a booleanType
 type Boolean struct {
+	nodePos
 	typ booleanType
 }
 
@@ -174,7 +241,7 @@ type Boolean struct {
 type booleanType int
 
 const (
-	booleanTypeTrue = iota
+	booleanTypeTrue booleanType = iota
 	booleanTypeFalse
 )
 
@@ -195,7 +262,7 @@ func (b *Boolean) String() string {
 }
 
 // NewBoolean returns a new Boolean
-func NewBoolean(strBool string) *Boolean {
+func NewBoolean(strBool string) (*Boolean, error) {
 	boolean := new(Boolean)
 
 	switch strBool {
@@ -204,10 +271,10 @@ func NewBoolean(strBool string) *Boolean {
 	case "false":
 		boolean.typ = booleanTypeFalse
 	default:
-		log.Fatal("Could not parse boolean")
+		return nil, newParseError("Could not parse boolean %s", strBool)
 	}
 
-	return boolean
+	return boolean, nil
 }
 
 // NewBooleanFromBool returns a new Boolean from a bool
@@ -226,6 +293,7 @@ func NewBooleanFromBool(value bool) *Boolean {
 
 // Paren is a struct with a parenTytpe
 type Paren struct {
+	nodePos
 	typ parenType
 }
 
@@ -234,7 +302,7 @@ type Paren struct {
 type parenType int
 
 const (
-	parenTypeOpen = iota
+	parenTypeOpen parenType = iota
 	parenTypeClose
 	parenTypeNil
 )
@@ -255,7 +323,7 @@ func (p *Paren) String() string {
 }
 
 // NewParen returns a new Paren
-func NewParen(strParen string) *Paren {
+func NewParen(strParen string) (*Paren, error) {
 	paren := new(Paren)
 
 	switch strParen {
@@ -264,15 +332,211 @@ func NewParen(strParen string) *Paren {
 	case ")":
 		paren.typ = parenTypeClose
 	default:
-		log.Fatal("Could not parse paren")
+		return nil, newParseError("Could not parse paren %s", strParen)
+	}
+
+	return paren, nil
+}
+
+// Bracket is a struct with a parenType that
+// either opens and closes a List literal or
+// an index into a collection
+type Bracket struct {
+	nodePos
+	typ   parenType
+	index bool
+}
+
+// GetType returns nodeTypeBracket
+func (b *Bracket) GetType() nodeType {
+	return nodeTypeBracket
+}
+
+// String returns a Bracket as a string
+func (b *Bracket) String() string {
+	switch b.typ {
+	case parenTypeOpen:
+		return "["
+	default:
+		return "]"
+	}
+}
+
+// NewBracket returns a new Bracket
+func NewBracket(strBracket string) (*Bracket, error) {
+	bracket := new(Bracket)
+
+	switch strBracket {
+	case "[":
+		bracket.typ = parenTypeOpen
+	case "]":
+		bracket.typ = parenTypeClose
+	default:
+		return nil, newParseError("Could not parse bracket %s", strBracket)
+	}
+
+	return bracket, nil
+}
+
+// Brace is a struct with a parenType
+// that opens or closes a Map literal
+type Brace struct {
+	nodePos
+	typ parenType
+}
+
+// GetType returns nodeTypeBrace
+func (b *Brace) GetType() nodeType {
+	return nodeTypeBrace
+}
+
+// String returns a Brace as a string
+func (b *Brace) String() string {
+	switch b.typ {
+	case parenTypeOpen:
+		return "{"
+	default:
+		return "}"
 	}
+}
+
+// NewBrace returns a new Brace
+func NewBrace(strBrace string) (*Brace, error) {
+	brace := new(Brace)
+
+	switch strBrace {
+	case "{":
+		brace.typ = parenTypeOpen
+	case "}":
+		brace.typ = parenTypeClose
+	default:
+		return nil, newParseError("Could not parse brace %s", strBrace)
+	}
+
+	return brace, nil
+}
+
+// Index is a struct that stores a collection
+// and a key. It is only looked up when its
+// value is needed, so it can be assigned to
+type Index struct {
+	nodePos
+	collection Node
+	key        Node
+}
+
+// GetType returns nodeTypeIndex
+func (i *Index) GetType() nodeType {
+	return nodeTypeIndex
+}
+
+// String returns the Index as code
+func (i *Index) String() string {
+	return fmt.Sprintf("%v[%v]", i.collection, i.key)
+}
+
+// NewIndex returns a new Index
+func NewIndex(collection Node, key Node) *Index {
+	return &Index{collection: collection, key: key}
+}
+
+// NamedArg is a struct that stores the name an
+// argument is passed by in a function call. In
+// RPN it has no value and follows the value
+// it names, which it then stores
+type NamedArg struct {
+	nodePos
+	name  string
+	value Node
+}
+
+// GetType returns nodeTypeNamedArg
+func (na *NamedArg) GetType() nodeType {
+	return nodeTypeNamedArg
+}
+
+// String returns the NamedArg as code
+func (na *NamedArg) String() string {
+	if na.value == nil {
+		return na.name + " ="
+	}
+
+	return fmt.Sprintf("%s = %v", na.name, na.value)
+}
+
+// NewNamedArg returns a new NamedArg
+func NewNamedArg(name string, value Node) *NamedArg {
+	return &NamedArg{name: name, value: value}
+}
+
+// Tuple is a struct that stores the values of a
+// comma separated expression, like the targets and
+// values of `a, b = b, a`. In RPN it only counts
+// the values before it, which it then stores
+type Tuple struct {
+	nodePos
+	count  int
+	values []Node
+}
+
+// GetType returns nodeTypeTuple
+func (t *Tuple) GetType() nodeType {
+	return nodeTypeTuple
+}
+
+// String returns the Tuple as code, or
+// its count if it has no values yet
+func (t *Tuple) String() string {
+	if t.values == nil {
+		return fmt.Sprintf(", %d", t.count)
+	}
+
+	values := make([]string, len(t.values))
+	for i, value := range t.values {
+		values[i] = value.String()
+	}
+
+	return strings.Join(values, ", ")
+}
+
+// NewTuple returns a new Tuple
+// holding `values`
+func NewTuple(values []Node) *Tuple {
+	return &Tuple{count: len(values), values: values}
+}
+
+// ShortCircuit is a struct that follows the left
+// side of a && or || in RPN. When the left side
+// decides the result, the right side is skipped
+// up to the operator
+type ShortCircuit struct {
+	nodePos
+	op *Operator
+}
+
+// GetType returns nodeTypeShortCircuit
+func (sc *ShortCircuit) GetType() nodeType {
+	return nodeTypeShortCircuit
+}
 
-	return paren
+// String returns the operator
+// followed by a `?`
+func (sc *ShortCircuit) String() string {
+	return sc.op.String() + "?"
+}
+
+// NewShortCircuit returns a new
+// ShortCircuit for `op`
+func NewShortCircuit(op *Operator) *ShortCircuit {
+	sc := &ShortCircuit{op: op}
+	sc.setPos(op.Pos())
+	return sc
 }
 
 // String is a struct that
 // stores a string
 type String struct {
+	nodePos
 	value string
 }
 
@@ -281,9 +545,10 @@ func (s *String) GetType() nodeType {
 	return nodeTypeString
 }
 
-// String returns the string wrapped in quotes
+// String returns the string wrapped in
+// quotes, with escapes where needed
 func (s *String) String() string {
-	return fmt.Sprintf("\"%s\"", s.value)
+	return strconv.Quote(s.value)
 }
 
 // NewString returns a new string
@@ -293,10 +558,65 @@ func NewString(s string) *String {
 	return str
 }
 
+// Template is a struct that stores the text of a
+// string literal with `${expr}` in it, and each
+// expression in RPN. It is evaluated as a String
+type Template struct {
+	nodePos
+	code  string
+	texts []string
+	exprs []*NodeStream
+}
+
+// GetType returns nodeTypeTemplate
+func (t *Template) GetType() nodeType {
+	return nodeTypeTemplate
+}
+
+// String returns the Template as code
+func (t *Template) String() string {
+	return t.code
+}
+
+// NewTemplate returns a new Template from the
+// code of a template literal and its parts,
+// which alternate between text and the code
+// of an expression
+func NewTemplate(code string, parts []string) (*Template, error) {
+	t := &Template{code: code}
+
+	for i, part := range parts {
+		if i%2 == 0 {
+			t.texts = append(t.texts, part)
+			continue
+		}
+
+		ns, err := NewNodeStreamFromLexer(Lex(part))
+		if err != nil {
+			return nil, err
+		}
+
+		if ns.Length() == 0 {
+			return nil, newParseError("Expected expression in ${} in %s", code)
+		}
+
+		rpn, err := NewNodeStreamInRPN(ns)
+		if err != nil {
+			return nil, err
+		}
+
+		t.exprs = append(t.exprs, rpn)
+	}
+
+	return t, nil
+}
+
 // FunctionCall is a struct
 // that stores the name of
-// a function
+// a function. Without a name
+// it calls the value before it
 type FunctionCall struct {
+	nodePos
 	name string
 }
 
@@ -318,11 +638,14 @@ func NewFunctionCall(strName string) *FunctionCall {
 	return f
 }
 
-// Variable is a struct that
-// stores a name and Node
+// Variable is a struct that stores a
+// name and Node, and whether it is
+// declared with `local`
 type Variable struct {
+	nodePos
 	name  string
 	value Node
+	local bool
 }
 
 // GetType returns nodeTypeVariable
@@ -342,8 +665,11 @@ func NewVariable(strName string) *Variable {
 	return v
 }
 
-// Comma is an empty struct
-type Comma struct{}
+// Comma is a struct that
+// stores only a position
+type Comma struct {
+	nodePos
+}
 
 // NewComma returns a new Comma
 func NewComma() *Comma {
@@ -360,8 +686,31 @@ func (c *Comma) String() string {
 	return ","
 }
 
+// Colon is a struct that
+// stores only a position
+type Colon struct {
+	nodePos
+}
+
+// NewColon returns a new Colon
+func NewColon() *Colon {
+	return new(Colon)
+}
+
+// GetType returns nodeTypeColon
+func (c *Colon) GetType() nodeType {
+	return nodeTypeColon
+}
+
+// String returns a `:`
+func (c *Colon) String() string {
+	return ":"
+}
+
 // nodeNil is used for undefined behavior
-type nodeNil struct{}
+type nodeNil struct {
+	nodePos
+}
 
 // GetType returns nodeTypeUnknown
 func (n *nodeNil) GetType() nodeType {
@@ -389,6 +738,12 @@ func (a ArgCount) String() string {
 	return fmt.Sprintf("%d", int(a))
 }
 
+// Pos returns an unknown position, since an
+// ArgCount is not lexed from the code
+func (a ArgCount) Pos() Pos {
+	return Pos{}
+}
+
 // NewArgCount returns a new ArgCount
 func NewArgCount(count int) ArgCount {
 	return ArgCount(count)
@@ -398,6 +753,7 @@ func NewArgCount(count int) ArgCount {
 // for reserved words and
 // stores the reserved word
 type Reserved struct {
+	nodePos
 	value string
 }
 
@@ -406,77 +762,138 @@ func (r *Reserved) GetType() nodeType {
 	return nodeTypeReserved
 }
 
-// String returns <RESERVED>
+// String returns the reserved word
 func (r *Reserved) String() string {
-	return "<RESERVED>"
+	return r.value
 }
 
 // NewReserved returns a new
 // reserved word
-func NewReserved() *Reserved {
-	return &Reserved{}
+func NewReserved(value string) *Reserved {
+	return &Reserved{value: value}
 }
 
-// NumberFromNode returns a float64
+// isReserved determines if `node` is
+// the reserved word `value`
+func isReserved(node Node, value string) bool {
+	if reserved, ok := node.(*Reserved); ok {
+		return reserved.value == value
+	}
+
+	return false
+}
+
+// Float64FromNode returns a float64
 // from a Node
-func Float64FromNode(node Node) float64 {
+func Float64FromNode(node Node) (float64, error) {
 	switch node.GetType() {
-	case nodeTypeVariable:
-		if scopeIsInitalized {
-			v, err := GetVar(node.(*Variable).name)
-			if err == nil {
-				return v.(*Number).value
-			} else {
-				log.Fatalf(err.Error())
-			}
-		} else {
-			log.Fatal("Cannot use variable (scope not init")
-		}
 	case nodeTypeNumber:
-		return node.(*Number).value
+		return node.(*Number).value, nil
 	case nodeTypeBoolean:
-		switch node.(*Boolean).typ {
-		case booleanTypeTrue:
-			return 1.0
-		case booleanTypeFalse:
-			return 0.0
+		if node.(*Boolean).typ == booleanTypeTrue {
+			return 1.0, nil
 		}
-	default:
-		log.Fatalf("Could not get numerical value from %v", node)
+
+		return 0.0, nil
 	}
-	return 0.0
+
+	return 0.0, newRuntimeError("Could not get numerical value from %v", node)
+}
+
+// IntFromNode returns an int from a Node
+// holding a whole number in the range of an int
+func IntFromNode(node Node) (int, error) {
+	num, err := Float64FromNode(node)
+	if err != nil {
+		return 0, err
+	}
+
+	// float64(math.MaxInt) rounds up to 2^63,
+	// which is already out of range
+	if num != math.Trunc(num) || math.Abs(num) >= math.MaxInt {
+		return 0, newRuntimeError("Expected a whole number, got %v", node)
+	}
+
+	return int(num), nil
 }
 
 // StringFromNode returns a string a Node
-func StringFromNode(node Node) string {
+func StringFromNode(node Node) (string, error) {
 	switch node.GetType() {
-	case nodeTypeNumber, nodeTypeBoolean:
-		return node.String()
+	case nodeTypeNumber, nodeTypeBoolean, nodeTypeList,
+		nodeTypeMap, nodeTypeFunction:
+		return node.String(), nil
 	case nodeTypeString:
-		return node.(*String).value
+		return node.(*String).value, nil
+	}
+
+	return "", newRuntimeError("Could not get string from %v", node)
+}
+
+// collectionString returns a List or Map as a
+// literal, writing `[...]` or `{...}` for one
+// inside itself so printing it does not
+// recurse forever
+func collectionString(node Node, seen map[Node]bool) string {
+	switch c := node.(type) {
+	case *List:
+		if seen[c] {
+			return "[...]"
+		}
+
+		seen[c] = true
+		defer delete(seen, c)
+
+		strs := make([]string, len(c.values))
+		for i, value := range c.values {
+			strs[i] = collectionString(value, seen)
+		}
+
+		return "[" + strings.Join(strs, ", ") + "]"
+	case *Map:
+		if seen[c] {
+			return "{...}"
+		}
+
+		seen[c] = true
+		defer delete(seen, c)
+
+		strs := make([]string, len(c.keys))
+		for i, key := range c.keys {
+			value, _ := c.Get(key)
+			strs[i] = key.String() + ": " + collectionString(value, seen)
+		}
+
+		return "{" + strings.Join(strs, ", ") + "}"
 	}
 
-	log.Fatalf("Could not get string from %v", node)
-	return ""
+	return node.String()
+}
+
+// isTruthy determines if a Node counts as true
+// for conditions, `!`, && and ||. Booleans and
+// Numbers have their boolean value, nil is
+// false and the rest true
+func isTruthy(node Node) bool {
+	switch node.GetType() {
+	case nodeTypeBoolean, nodeTypeNumber:
+		value, _ := BooleanFromNode(node)
+		return value
+	case nodeTypeUnkown:
+		return false
+	}
+
+	return true
 }
 
 // BooleanFromNode returns a bool from a Node
-func BooleanFromNode(node Node) bool {
+func BooleanFromNode(node Node) (bool, error) {
 	switch node.GetType() {
 	case nodeTypeBoolean:
-		switch node.(*Boolean).typ {
-		case booleanTypeTrue:
-			return true
-		case booleanTypeFalse:
-			return false
-		}
+		return node.(*Boolean).typ == booleanTypeTrue, nil
 	case nodeTypeNumber:
-		if node.(*Number).value == 0.0 {
-			return false
-		}
-		return true
+		return node.(*Number).value != 0.0, nil
 	}
 
-	log.Fatalf("Could not get boolean value from %v", node)
-	return false
+	return false, newRuntimeError("Could not get boolean value from %v", node)
 }
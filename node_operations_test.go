@@ -1,27 +1,90 @@
 package blast
 
 import (
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestTokenOperations(t *testing.T) {
-	ts := NewNodeStreamFromLexer(Lex("15.5 -120 true false \"derp\""))
+	ts := lexNodeStream(t, "15.5 120 true false \"derp\"")
 	flt := ts.Next()
-	neg := ts.Next()
+	neg, err := NegateNode(ts.Next())
+	assert.Nil(t, err)
 	//tru := ts.Next()
 	//fls := ts.Next()
 	//derp := ts.Next()
-	assert.Equal(t, -104.5, tokenValue(AddNodes(flt, neg)))
+	sum, err := AddNodes(flt, neg)
+	assert.Nil(t, err)
+	assert.Equal(t, -104.5, tokenValue(sum))
+
+	_, err = SubtractNodes(flt, NewString("derp"))
+	assert.IsType(t, &ErrRuntime{}, err)
+
+	_, err = ModNodes(flt, NewNumberFromFloat(0))
+	assert.IsType(t, &ErrRuntime{}, err)
+
+	_, err = ModNodes(NewNumberFromFloat(5), NewNumberFromFloat(0.5))
+	assert.EqualError(t, err, "Cannot take 5 modulo 0.5, which truncates to zero")
+
+	product, err := MultiplyNodes(NewString("ab"), NewNumberFromFloat(3))
+	assert.Nil(t, err)
+	assert.Equal(t, "ababab", tokenValue(product))
+
+	_, err = MultiplyNodes(NewString("a"), NewNumberFromFloat(1e300))
+	assert.IsType(t, &ErrRuntime{}, err)
+
+	_, err = MultiplyNodes(NewNumberFromFloat(1e18), NewString("a"))
+	assert.IsType(t, &ErrRuntime{}, err)
+
+	_, err = MultiplyNodes(NewString(""), NewNumberFromFloat(1e300))
+	assert.IsType(t, &ErrRuntime{}, err)
+
+	_, err = MultiplyNodes(NewString("a"), NewNumberFromFloat(math.NaN()))
+	assert.IsType(t, &ErrRuntime{}, err)
 }
 
 func tokenValue(token Node) interface{} {
 	switch token.GetType() {
 	case nodeTypeNumber, nodeTypeBoolean:
-		return Float64FromNode(token)
+		value, _ := Float64FromNode(token)
+		return value
 	case nodeTypeString:
-		return StringFromNode(token)
+		value, _ := StringFromNode(token)
+		return value
 	}
 	return nil
 }
+
+func TestRaiseNodes(t *testing.T) {
+	interp := NewInterpreter()
+
+	tests := []struct {
+		code   string
+		result string
+	}{
+		{"2 ^ 10", "1024"},
+		{"4 ^ 0.5", "2"},
+		{"2 ^ -1", "0.5"},
+		{"2 ^ -2 * 8", "2"},
+		{"2 ^ 3 ^ 2", "512"},
+		{"(2 ^ 3) ^ 2", "64"},
+		{"-2 ^ 2", "-4"},
+		{"(-2) ^ 2", "4"},
+		{"x = 2\n-x ^ 2", "-4"},
+		{"0 ^ -1", "+Inf"},
+		{"inf() ^ 0", "1"},
+		{"(-8) ^ (1 / 3)", "NaN"},
+		{"x ^= 3 ^ 2\nx", "512"},
+	}
+
+	for _, test := range tests {
+		result, err := interp.Run(test.code)
+		assert.Nil(t, err, test.code)
+		assert.Equal(t, test.result, result.String(), test.code)
+	}
+
+	_, err := interp.Run("\"a\" ^ 2")
+	assert.IsType(t, &ErrRuntime{}, err)
+}
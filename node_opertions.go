@@ -1,125 +1,245 @@
 package blast
 
 import (
-	"log"
+	"math"
 	"strings"
 )
 
-// AddNodes adds two Nodes into one Node
-func AddNodes(n1 Node, n2 Node) Node {
-	var result Node
+// maxRepeatLength is the longest String
+// that repeating a String can make
+const maxRepeatLength = 1 << 26
+
+// checkRepeatLength returns an error if `str`
+// repeated `count` times would be longer
+// than maxRepeatLength
+func checkRepeatLength(str string, count float64) error {
+	if math.IsNaN(count) || count > maxRepeatLength || float64(len(str))*count > maxRepeatLength {
+		return newRuntimeError("Cannot repeat a string of length %d %v times, the result would be too long", len(str), count)
+	}
 
+	return nil
+}
+
+// AddNodes adds two Nodes into one Node
+func AddNodes(n1 Node, n2 Node) (Node, error) {
 	if n1.GetType() == nodeTypeString || n2.GetType() == nodeTypeString {
-		result = NewString(StringFromNode(n1) + StringFromNode(n2))
-	} else {
-		result = NewNumberFromFloat(Float64FromNode(n1) + Float64FromNode(n2))
+		str1, err := StringFromNode(n1)
+		if err != nil {
+			return nil, err
+		}
+
+		str2, err := StringFromNode(n2)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewString(str1 + str2), nil
+	}
+
+	num1, num2, err := float64sFromNodes(n1, n2)
+	if err != nil {
+		return nil, err
 	}
 
-	return result
+	return NewNumberFromFloat(num1 + num2), nil
 }
 
 // SubtractNodes subtracts two Nodes into one Node
-func SubtractNodes(n1 Node, n2 Node) Node {
-	if n1.GetType() != nodeTypeString && n2.GetType() != nodeTypeString {
-		return NewNumberFromFloat(Float64FromNode(n1) - Float64FromNode(n2))
+func SubtractNodes(n1 Node, n2 Node) (Node, error) {
+	if n1.GetType() == nodeTypeString || n2.GetType() == nodeTypeString {
+		return nil, newRuntimeError("Cannot subtract %v and %v", n1, n2)
+	}
+
+	num1, num2, err := float64sFromNodes(n1, n2)
+	if err != nil {
+		return nil, err
 	}
 
-	log.Fatalf("Cannot subtract %v and %v", n1, n2)
-	return &nodeNil{}
+	return NewNumberFromFloat(num1 - num2), nil
 }
 
 // MultiplyNodes multiplies two Nodes into one Node
-func MultiplyNodes(n1 Node, n2 Node) Node {
-	if n1.GetType() == nodeTypeString {
+func MultiplyNodes(n1 Node, n2 Node) (Node, error) {
+	if n1.GetType() == nodeTypeString && n2.GetType() == nodeTypeString {
+		return nil, newRuntimeError("Cannot multiply %v and %v", n1, n2)
+	}
+
+	// A string multiplied by a number
+	// is the string repeated
+	if n1.GetType() == nodeTypeString || n2.GetType() == nodeTypeString {
+		str, count := n1, n2
 		if n2.GetType() == nodeTypeString {
-			log.Fatalf("Cannot multiply tokens %v and %v", n1, n2)
-		} else {
-			return NewString(strings.Repeat(
-				StringFromNode(n1), int(Float64FromNode(n2))))
+			str, count = n2, n1
 		}
-	} else {
-		if n2.GetType() != nodeTypeString {
-			return NewNumberFromFloat(Float64FromNode(n1) * Float64FromNode(n2))
-		} else {
-			return NewString(strings.Repeat(StringFromNode(n2), int(Float64FromNode(n1))))
+
+		strValue, err := StringFromNode(str)
+		if err != nil {
+			return nil, err
+		}
+
+		countValue, err := Float64FromNode(count)
+		if err != nil {
+			return nil, err
+		}
+
+		if countValue < 0 {
+			return nil, newRuntimeError("Cannot repeat %v a negative number of times", str)
+		}
+
+		if err := checkRepeatLength(strValue, countValue); err != nil {
+			return nil, err
 		}
+
+		return NewString(strings.Repeat(strValue, int(countValue))), nil
 	}
 
-	log.Fatalf("Could not multiply %v and %v", n1, n2)
-	return &nodeNil{}
-}
+	num1, num2, err := float64sFromNodes(n1, n2)
+	if err != nil {
+		return nil, err
+	}
 
-// RaiseNodes raises n1 to the power of n2 into one Node
-func RaiseNodes(n1 Node, n2 Node) Node {
-	result := 1.0
-	num1, num2 := Float64FromNode(n1), Float64FromNode(n2)
+	return NewNumberFromFloat(num1 * num2), nil
+}
 
-	for i := 0; i < int(num2); i++ {
-		result *= num1
+// RaiseNodes raises n1 to the power of n2 into one
+// Node. Like math.Pow, fractional and negative powers
+// are allowed, and a negative number to a fractional
+// power is NaN
+func RaiseNodes(n1 Node, n2 Node) (Node, error) {
+	num1, num2, err := float64sFromNodes(n1, n2)
+	if err != nil {
+		return nil, err
 	}
 
-	return NewNumberFromFloat(result)
+	return NewNumberFromFloat(math.Pow(num1, num2)), nil
 }
 
-// AssignNodes assigns the variable represented
-// by n1 to the value represented by n2
-func AssignNode(n1 Node, n2 Node) Node {
-	if n1.GetType() != nodeTypeVariable {
-		log.Fatalf("Could not assign %v to %v", n2, n1)
+// AssignNodes assigns the variable or index
+// represented by n1 to the value represented
+// by n2
+func (interp *Interpreter) AssignNode(n1 Node, n2 Node) (Node, error) {
+	switch n := n1.(type) {
+	case *Variable:
+		if n.local {
+			interp.SetLocal(n.name, n2)
+		} else {
+			interp.SetVar(n.name, n2)
+		}
+
+		return n2, nil
+	case *Index:
+		if err := SetIndexNode(n.collection, n.key, n2); err != nil {
+			return nil, err
+		}
+
+		return n2, nil
+	case *Tuple:
+		values, ok := n2.(*List)
+		if !ok {
+			return nil, newRuntimeError("Cannot unpack %v into %v", n2, n1)
+		}
+
+		if len(values.values) != len(n.values) {
+			return nil, newRuntimeError("Cannot unpack %d values into %d targets", len(values.values), len(n.values))
+		}
+
+		// The values are copied first so
+		// the List can be one of the targets
+		items := make([]Node, len(values.values))
+		copy(items, values.values)
+		for i, target := range n.values {
+			if _, err := interp.AssignNode(target, items[i]); err != nil {
+				return nil, err
+			}
+		}
+
+		return n2, nil
 	}
 
-	if v, ok := n1.(*Variable); ok {
-		SetVar(v.name, n2)
-		return n2
+	return nil, newRuntimeError("Could not assign %v to %v", n2, n1)
+}
+
+// NegateNode returns the negative of a Number
+func NegateNode(n Node) (Node, error) {
+	if n.GetType() != nodeTypeNumber {
+		return nil, newRuntimeError("Cannot negate %v", n)
 	}
 
-	log.Fatalf("Could not assign %v to %v", n2, n1)
-	return &nodeNil{}
+	return NewNumberFromFloat(-n.(*Number).value), nil
+}
+
+// NotNode returns the opposite
+// of whether a Node is truthy
+func NotNode(n Node) (Node, error) {
+	return NewBooleanFromBool(!isTruthy(n)), nil
 }
 
 // DivideNodes divides two Nodes into one
-func DivideNodes(n1 Node, n2 Node) Node {
-	if n1.GetType() != nodeTypeString && n2.GetType() != nodeTypeString {
-		return NewNumberFromFloat(Float64FromNode(n1) / Float64FromNode(n2))
+func DivideNodes(n1 Node, n2 Node) (Node, error) {
+	if n1.GetType() == nodeTypeString || n2.GetType() == nodeTypeString {
+		return nil, newRuntimeError("Cannot divide %v and %v", n1, n2)
+	}
+
+	num1, num2, err := float64sFromNodes(n1, n2)
+	if err != nil {
+		return nil, err
 	}
 
-	log.Fatalf("Cannot divide %v and %v", n1, n2)
-	return &nodeNil{}
+	return NewNumberFromFloat(num1 / num2), nil
 }
 
 // ModeNodes takes the modulus and returns
 // the result in a node
-func ModNodes(n1 Node, n2 Node) Node {
+func ModNodes(n1 Node, n2 Node) (Node, error) {
 	if n1.GetType() != nodeTypeNumber || n2.GetType() != nodeTypeNumber {
-		log.Fatalf("Cannot perform modulus operation with non-numbers")
+		return nil, newRuntimeError("Cannot perform modulus operation with non-numbers")
+	}
+
+	num1, num2, err := float64sFromNodes(n1, n2)
+	if err != nil {
+		return nil, err
+	}
+
+	// The operands are truncated to whole numbers,
+	// so a fraction like 0.5 is a zero divisor too
+	if num2 == 0 {
+		return nil, newRuntimeError("Cannot take %v modulo zero", n1)
+	}
+
+	if int(num2) == 0 {
+		return nil, newRuntimeError("Cannot take %v modulo %v, which truncates to zero", n1, n2)
 	}
 
-	return NewNumberFromFloat(float64(int(Float64FromNode(n1)) % int(Float64FromNode(n2))))
+	return NewNumberFromFloat(float64(int(num1) % int(num2))), nil
 }
 
 // CompareNodes compares two Nodes opNode and returns a
 // Boolean Node
-func CompareNodes(n1 Node, n2 Node, tokOp Node) Node {
+func CompareNodes(n1 Node, n2 Node, tokOp Node) (Node, error) {
 	var result bool
 	var num1, num2 float64
+	var err error
 
 	op, ok := tokOp.(*Operator)
 
 	if !ok {
-		log.Fatalf("Cannot compare with operator %v", tokOp)
+		return nil, newRuntimeError("Cannot compare with operator %v", tokOp)
 	}
 
 	// If the operator is not == or != then get
 	// the numerical values from the Nodes
 	if op.typ != opTypeEqualTo && op.typ != opTypeNotEqualTo {
-		num1, num2 = Float64FromNode(n1), Float64FromNode(n2)
+		if num1, num2, err = float64sFromNodes(n1, n2); err != nil {
+			return nil, err
+		}
 	}
 
 	switch op.typ {
 	case opTypeEqualTo:
-		result = nodeIsEqualTo(n1, n2)
+		result, err = nodeIsEqualTo(n1, n2, make(map[[2]Node]bool))
 	case opTypeNotEqualTo:
-		result = !nodeIsEqualTo(n1, n2)
+		result, err = nodeIsEqualTo(n1, n2, make(map[[2]Node]bool))
+		result = !result
 	case opTypeLessThan:
 		result = num1 < num2
 	case opTypeLessThanOrEqualTo:
@@ -128,27 +248,119 @@ func CompareNodes(n1 Node, n2 Node, tokOp Node) Node {
 		result = num1 > num2
 	case opTypeGreaterThanOrEqualTo:
 		result = num1 >= num2
-	// TODO: make these their own operation category
-	case opTypeAnd:
-		result = num1 != 0.0 && num2 != 0.0
-	case opTypeOr:
-		result = num1 != 0.0 || num2 != 0.0
 	}
 
-	return NewBooleanFromBool(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewBooleanFromBool(result), nil
+}
+
+// IndexNode returns the item of a List or Map, or
+// the character of a String at the index `key`
+func IndexNode(collection Node, key Node) (Node, error) {
+	switch c := collection.(type) {
+	case *List:
+		i, err := listIndex(c, key)
+		if err != nil {
+			return nil, err
+		}
+
+		return c.values[i], nil
+	case *Map:
+		return c.Get(key)
+	case *String:
+		runes := []rune(c.value)
+		i, err := IntFromNode(key)
+		if err != nil {
+			return nil, err
+		}
+
+		if i < 0 || i >= len(runes) {
+			return nil, newRuntimeError("Index %d out of range for string of length %d", i, len(runes))
+		}
+
+		return NewString(string(runes[i])), nil
+	}
+
+	return nil, newRuntimeError("Cannot index %v", collection)
+}
+
+// SetIndexNode sets the item of a List
+// or Map at the index `key` to `value`
+func SetIndexNode(collection Node, key Node, value Node) error {
+	switch c := collection.(type) {
+	case *List:
+		i, err := listIndex(c, key)
+		if err != nil {
+			return err
+		}
+
+		c.values[i] = value
+		return nil
+	case *Map:
+		return c.Set(key, value)
+	}
+
+	return newRuntimeError("Cannot assign to an index of %v", collection)
 }
 
-// tokenIsEqual compares two Nodes and determine if they're equal
-func nodeIsEqualTo(n1 Node, n2 Node) bool {
+// tokenIsEqual compares two Nodes and determine if they're equal.
+// `seen` holds the pairs of collections already being compared
+func nodeIsEqualTo(n1 Node, n2 Node, seen map[[2]Node]bool) (bool, error) {
+	if n1.GetType() == nodeTypeList || n2.GetType() == nodeTypeList {
+		if n1.GetType() != n2.GetType() {
+			return false, nil
+		}
+
+		return listIsEqualTo(n1.(*List), n2.(*List), seen)
+	}
+
+	if n1.GetType() == nodeTypeFunction || n2.GetType() == nodeTypeFunction {
+		if n1.GetType() != n2.GetType() {
+			return false, nil
+		}
+
+		return n1.(*FunctionValue).f == n2.(*FunctionValue).f, nil
+	}
+
+	if n1.GetType() == nodeTypeMap || n2.GetType() == nodeTypeMap {
+		if n1.GetType() != n2.GetType() {
+			return false, nil
+		}
+
+		return mapIsEqualTo(n1.(*Map), n2.(*Map), seen)
+	}
+
 	if n1.GetType() == nodeTypeString || n2.GetType() == nodeTypeString {
 		if n1.GetType() != n2.GetType() {
-			return false
+			return false, nil
 		}
+
+		return n1.(*String).value == n2.(*String).value, nil
+	}
+
+	num1, num2, err := float64sFromNodes(n1, n2)
+	if err != nil {
+		return false, err
+	}
+
+	return num1 == num2, nil
+}
+
+// float64sFromNodes returns the numerical
+// values of two Nodes
+func float64sFromNodes(n1 Node, n2 Node) (float64, float64, error) {
+	num1, err := Float64FromNode(n1)
+	if err != nil {
+		return 0.0, 0.0, err
 	}
 
-	if n1.GetType() == nodeTypeString {
-		return StringFromNode(n1) == StringFromNode(n2)
+	num2, err := Float64FromNode(n2)
+	if err != nil {
+		return 0.0, 0.0, err
 	}
 
-	return Float64FromNode(n1) == Float64FromNode(n2)
+	return num1, num2, nil
 }
@@ -1,5 +1,7 @@
 package blast
 
+import "strings"
+
 // NodeStream is a Node slice
 // wrapper with stack and
 // queue funcitonality
@@ -80,6 +82,10 @@ func (ns *NodeStream) Backup() *NodeStream {
 // Peek returns the next Node without
 // incrementing the position
 func (ns *NodeStream) Peek() Node {
+	if !ns.HasNext() {
+		return &nodeNil{}
+	}
+
 	n := ns.Next()
 	ns.Backup()
 	return n
@@ -87,9 +93,13 @@ func (ns *NodeStream) Peek() Node {
 
 // Evaluate converns the NodeStream
 // to RPN notation and evaluates it
-func (ns *NodeStream) Evaluate() Node {
-	rpn := NewNodeStreamInRPN(ns)
-	return EvaluateRPN(rpn)
+func (ns *NodeStream) Evaluate(interp *Interpreter) (Node, error) {
+	rpn, err := NewNodeStreamInRPN(ns)
+	if err != nil {
+		return nil, err
+	}
+
+	return interp.EvaluateRPN(rpn)
 }
 
 // String returns a string representation
@@ -123,6 +133,13 @@ func (ns *NodeStream) Chop() *NodeStream {
 	return newns
 }
 
+// Clone returns a new NodeStream with the same
+// Nodes, so they can be read without moving
+// the position of the NodeStream
+func (ns *NodeStream) Clone() *NodeStream {
+	return &NodeStream{size: ns.size, nodes: ns.nodes}
+}
+
 // Reverse reverses the order
 // of the Nodes in the NodeStream
 func (ns *NodeStream) Reverse() {
@@ -146,34 +163,116 @@ func (ns *NodeStream) Reset() {
 
 // NewNodeStreamFromLexer returns a new NodeStream
 // from a lexer that already has a slice of Tokens
-func NewNodeStreamFromLexer(l *Lexer) *NodeStream {
+func NewNodeStreamFromLexer(l *Lexer) (*NodeStream, error) {
+	var node Node
+	var err error
 	ns := NewNodeStream()
 
+	if l.Err() != nil {
+		return nil, l.Err()
+	}
+
 	for l.HasNextItem() {
-		switch item := l.NextItem(); item.typ {
+		item := l.NextItem()
+		pos := item.pos
+
+		switch item.typ {
 		case tokenTypeNum:
-			ns.Push(NewNumber(item.text))
+			// A negative number is a prefix `-` before
+			// the number, so `-2 ^ 2` is `-(2 ^ 2)`
+			text := item.text
+			if strings.HasPrefix(text, "-") {
+				neg, _ := NewOperator("-")
+				neg.setPos(pos)
+				ns.Push(neg)
+				text = text[1:]
+				pos.Col++
+			}
+
+			node, err = NewNumber(text)
 		case tokenTypeBool:
-			ns.Push(NewBoolean(item.text))
+			node, err = NewBoolean(item.text)
 		case tokenTypeString:
-			ns.Push(NewString(item.text))
+			node = NewString(item.text)
+		case tokenTypeTemplate:
+			node, err = NewTemplate(item.text, item.parts)
 		case tokenTypeOperator:
-			ns.Push(NewOperator(item.text))
+			node, err = NewOperator(item.text)
 		case tokenTypeOpenParen, tokenTypeCloseParen:
-			ns.Push(NewParen(item.text))
+			// A `(` after a `)`, `]` or `end` calls
+			// the function that the value before it is
+			if item.typ == tokenTypeOpenParen && isCallable(ns.Top()) {
+				call := NewFunctionCall("")
+				call.setPos(item.pos)
+				ns.Push(call)
+			}
+
+			node, err = NewParen(item.text)
+		case tokenTypeOpenBracket, tokenTypeCloseBracket:
+			var bracket *Bracket
+			if bracket, err = NewBracket(item.text); err == nil {
+				bracket.index = isIndexable(ns.Top())
+				node = bracket
+			}
+		case tokenTypeOpenBrace, tokenTypeCloseBrace:
+			node, err = NewBrace(item.text)
 		case tokenTypeComma:
-			ns.Push(NewComma())
+			node = NewComma()
+		case tokenTypeColon:
+			node = NewColon()
 		case tokenTypeIdentifier:
 			if l.HasNextItem() && l.PeekItem().typ == tokenTypeOpenParen {
-				ns.Push(NewFunctionCall(item.text))
+				node = NewFunctionCall(item.text)
 			} else {
-				ns.Push(NewVariable(item.text))
+				node = NewVariable(item.text)
 			}
 		default:
-			ns.Push(NewReserved())
+			node = NewReserved(item.text)
+		}
+
+		if err != nil {
+			l.tokenPos = 0
+			return nil, errorAt(err, item.pos)
 		}
+
+		node.(posSetter).setPos(pos)
+		ns.Push(node)
 	}
 
 	l.tokenPos = 0
-	return ns
+	return ns, nil
+}
+
+// isIndexable determines if a `[` after `node`
+// indexes the value before it rather than
+// starting a List literal
+func isIndexable(node Node) bool {
+	switch n := node.(type) {
+	case *Variable, *String, *Template:
+		return true
+	case *Paren:
+		return n.typ == parenTypeClose
+	case *Bracket:
+		return n.typ == parenTypeClose
+	case *Brace:
+		return n.typ == parenTypeClose
+	}
+
+	return false
+}
+
+// isCallable determines if a `(` after `node`
+// calls the value before it. An `end` is the
+// end of an anonymous function
+func isCallable(node Node) bool {
+	switch n := node.(type) {
+	case *Paren:
+		return n.typ == parenTypeClose
+	case *Bracket:
+		return n.typ == parenTypeClose
+	case *Reserved:
+		return n.value == "end"
+	}
+
+	return false
 }
@@ -1,18 +1,19 @@
 package blast
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestTokenStream(t *testing.T) {
-	ts := NewNodeStreamFromLexer(Lex("200.98 + 300"))
+	ts := lexNodeStream(t, "200.98 + 300")
 	assert.Equal(t, nodeTypeNumber, ts.Next().GetType())
 	assert.Equal(t, nodeTypeOperator, ts.Next().GetType())
 	assert.Equal(t, nodeTypeNumber, ts.Next().GetType())
 
-	ts = NewNodeStreamFromLexer(Lex("true false \"derpsause\" + 300 == 41 && x <= y(220)"))
+	ts = lexNodeStream(t, "true false \"derpsause\" + 300 == 41 && x <= y(220)")
 	assert.Equal(t, nodeTypeBoolean, ts.Next().GetType())
 	assert.Equal(t, nodeTypeBoolean, ts.Next().GetType())
 	assert.Equal(t, nodeTypeString, ts.Next().GetType())
@@ -25,7 +26,7 @@ func TestTokenStream(t *testing.T) {
 	assert.Equal(t, nodeTypeOperator, ts.Next().GetType())
 	assert.Equal(t, nodeTypeFuncCall, ts.Next().GetType())
 
-	ts = NewNodeStreamFromLexer(Lex("return n1 + n2"))
+	ts = lexNodeStream(t, "return n1 + n2")
 	assert.Equal(t, nodeTypeReserved, ts.Next().GetType())
 	assert.Equal(t, nodeTypeVariable, ts.Next().GetType())
 	assert.Equal(t, nodeTypeOperator, ts.Next().GetType())
@@ -33,24 +34,85 @@ func TestTokenStream(t *testing.T) {
 }
 
 func TestTokenConversions(t *testing.T) {
-	ts := NewNodeStreamFromLexer(Lex("200.9 false true \"derp\""))
+	ts := lexNodeStream(t, "200.9 false true \"derp\"")
 	num := ts.Next()
 	fls := ts.Next()
 	tru := ts.Next()
 	str := ts.Next()
 
 	// Numbers
-	assert.Equal(t, 200.9, Float64FromNode(num))
-	assert.Equal(t, 0.0, Float64FromNode(fls))
-	assert.Equal(t, 1.0, Float64FromNode(tru))
+	assertFloat64FromNode(t, 200.9, num)
+	assertFloat64FromNode(t, 0.0, fls)
+	assertFloat64FromNode(t, 1.0, tru)
 
 	// Bools
-	assert.Equal(t, true, BooleanFromNode(tru))
-	assert.Equal(t, false, BooleanFromNode(fls))
+	assertBooleanFromNode(t, true, tru)
+	assertBooleanFromNode(t, false, fls)
 
 	// Strings
-	assert.Equal(t, "200.9", StringFromNode(num))
-	assert.Equal(t, "false", StringFromNode(fls))
-	assert.Equal(t, "true", StringFromNode(tru))
-	assert.Equal(t, "derp", StringFromNode(str))
+	assertStringFromNode(t, "200.9", num)
+	assertStringFromNode(t, "false", fls)
+	assertStringFromNode(t, "true", tru)
+	assertStringFromNode(t, "derp", str)
+
+	// Errors
+	_, err := Float64FromNode(str)
+	assert.IsType(t, &ErrRuntime{}, err)
+
+	_, err = BooleanFromNode(str)
+	assert.IsType(t, &ErrRuntime{}, err)
+}
+
+func lexNodeStream(t *testing.T, code string) *NodeStream {
+	ns, err := NewNodeStreamFromLexer(Lex(code))
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	return ns
+}
+
+func assertFloat64FromNode(t *testing.T, expected float64, node Node) {
+	actual, err := Float64FromNode(node)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func assertBooleanFromNode(t *testing.T, expected bool, node Node) {
+	actual, err := BooleanFromNode(node)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func assertStringFromNode(t *testing.T, expected string, node Node) {
+	actual, err := StringFromNode(node)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func TestTemplateEvaluation(t *testing.T) {
+	interp := NewInterpreter()
+
+	result, err := interp.Run(strings.Join([]string{
+		`x = 4`,
+		`a = 1`,
+		`b = 2.5`,
+		`xs = [1, "two"]`,
+		`function greet(name)`,
+		`  return "hi ${name}"`,
+		`end`,
+		`"x is ${x} and sum ${a + b}, ${xs} ${greet("${x}")} ${x > 3}"`,
+	}, "\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, `x is 4 and sum 3.5, [1, "two"] hi 4 true`, result.(*String).value)
+
+	_, err = interp.Run(`"${missing}"`)
+	assert.EqualError(t, err, "1:1: Variable missing not found")
+
+	_, err = interp.Run(`y = 1 + "${1 +}"`)
+	assert.IsType(t, &ErrParse{}, err)
+
+	_, err = interp.Run(`"${}"`)
+	assert.EqualError(t, err, `1:1: Expected expression in ${} in "${}"`)
 }
@@ -1,6 +1,6 @@
 package blast
 
-import "log"
+import "strings"
 
 // opPrecedenceMap is used to determine
 // the precedence of an operator
@@ -10,7 +10,9 @@ var opPrecedenceMap = map[opType]int{
 	opTypeMultiplication:       3,
 	opTypeDivision:             3,
 	opTypeModulus:              3,
-	opTypeExponent:             4,
+	opTypeExponent:             5,
+	opTypeNegate:               4,
+	opTypeNot:                  4,
 	opTypeLessThan:             1,
 	opTypeLessThanOrEqualTo:    1,
 	opTypeEqualTo:              1,
@@ -20,28 +22,94 @@ var opPrecedenceMap = map[opType]int{
 	opTypeAnd:                  0,
 	opTypeOr:                   0,
 	opTypeAssignment:           -1,
+	opTypeAddAssign:            -1,
+	opTypeSubtractAssign:       -1,
+	opTypeMultiplyAssign:       -1,
+	opTypeDivideAssign:         -1,
+	opTypeModulusAssign:        -1,
+	opTypeExponentAssign:       -1,
+}
+
+// compoundOperators is used to get the
+// operator that a compound assignment
+// applies before it assigns
+var compoundOperators = map[opType]opType{
+	opTypeAddAssign:      opTypeAddition,
+	opTypeSubtractAssign: opTypeSubtraction,
+	opTypeMultiplyAssign: opTypeMultiplication,
+	opTypeDivideAssign:   opTypeDivision,
+	opTypeModulusAssign:  opTypeModulus,
+	opTypeExponentAssign: opTypeExponent,
 }
 
 // ForDeclaration is a struct
 // that holds all the parts
-// of a for loop
+// of a for loop. A for-in loop
+// has a collection instead of
+// a start, end and step
 type ForDeclaration struct {
-	start   float64
-	end     float64
-	step    float64
-	counter *Variable
+	start      float64
+	end        float64
+	step       float64
+	counter    *Variable
+	value      *Variable
+	collection Node
 }
 
 // OneLineIf stores the components
-// for a one line if test
+// for a one line if test, and is
+// evaluated as a value Node
 type OneLineIf struct {
+	nodePos
 	cond      *NodeStream
 	passBlock *NodeStream
 	elseBlock *NodeStream
 }
 
+// GetType returns nodeTypeOneLineIf
+func (oli *OneLineIf) GetType() nodeType {
+	return nodeTypeOneLineIf
+}
+
+// String returns the OneLineIf as code
+func (oli *OneLineIf) String() string {
+	str := "if " + oli.cond.String() + "then " + oli.passBlock.String()
+
+	if oli.elseBlock != nil {
+		str += "else " + oli.elseBlock.String()
+	}
+
+	return str
+}
+
+// Lambda stores an anonymous function, and is
+// evaluated as a FunctionValue closing over
+// the current scope
+type Lambda struct {
+	nodePos
+	function *UserFunction
+}
+
+// GetType returns nodeTypeLambda
+func (l *Lambda) GetType() nodeType {
+	return nodeTypeLambda
+}
+
+// String returns the Lambda as code
+func (l *Lambda) String() string {
+	params := make([]string, len(l.function.params))
+	for i, param := range l.function.params {
+		params[i] = param.name
+		if param.variadic {
+			params[i] = "..." + param.name
+		}
+	}
+
+	return "fn(" + strings.Join(params, ", ") + ") " + l.function.body.String() + "end"
+}
+
 // EvaluateRPN evaluates an RPN expression
-func EvaluateRPN(ts *NodeStream) Node {
+func (interp *Interpreter) EvaluateRPN(ts *NodeStream) (Node, error) {
 	var node Node
 	nodes := NewNodeStream()
 	for ts.HasNext() {
@@ -51,35 +119,237 @@ func EvaluateRPN(ts *NodeStream) Node {
 		case nodeTypeNumber, nodeTypeBoolean,
 			nodeTypeVariable, nodeTypeString:
 			nodes.Push(node)
+		// Push the String that a template evaluates
+		// to, with each expression formatted in it
+		case nodeTypeTemplate:
+			str, err := interp.EvaluateTemplate(node.(*Template))
+			if err != nil {
+				return nil, errorAt(err, node.Pos())
+			}
+
+			nodes.Push(str)
+		// Push a function closing over the
+		// current scope for an anonymous function
+		case nodeTypeLambda:
+			f := *node.(*Lambda).function
+			f.closure = interp.CurrScope()
+			fv := NewFunctionValue(f.name, &f)
+			fv.setPos(node.Pos())
+			nodes.Push(fv)
+		// Push the value of the branch that a
+		// one line if evaluates to
+		case nodeTypeOneLineIf:
+			result, err := interp.EvaluateOneLineIf(node.(*OneLineIf))
+			if err != nil {
+				return nil, err
+			}
+
+			nodes.Push(result)
+		// Pop the values of a tuple off the stack
+		// without evaluating them, since they may
+		// be the targets of an assignment
+		case nodeTypeTuple:
+			count := node.(*Tuple).count
+			if nodes.Length() < count {
+				return nil, errorAt(newParseError("Missing value for %v", node), node.Pos())
+			}
+
+			values := make([]Node, count)
+			for i := count - 1; i >= 0; i-- {
+				values[i] = nodes.Pop()
+			}
+
+			tuple := NewTuple(values)
+			tuple.setPos(node.Pos())
+			nodes.Push(tuple)
+		// Store the value a named argument is passed
+		// with, so the function can bind it by name
+		case nodeTypeNamedArg:
+			if nodes.Length() < 1 {
+				return nil, errorAt(newParseError("Missing value for %v", node), node.Pos())
+			}
+
+			value, err := interp.EvaluateNode(nodes.Pop())
+			if err != nil {
+				return nil, err
+			}
+
+			arg := NewNamedArg(node.(*NamedArg).name, value)
+			arg.setPos(node.Pos())
+			nodes.Push(arg)
 		// If an operator is detected, pop two Nodes
 		// off the stack and evaluate them
 		case nodeTypeOperator:
+			// A unary operator has one operand
+			if isUnary(node.(*Operator)) {
+				if nodes.Length() < 1 {
+					return nil, errorAt(newParseError("Missing operand for %v", node), node.Pos())
+				}
+
+				result, err := interp.EvaluateUnary(nodes.Pop(), node)
+				if err != nil {
+					return nil, errorAt(err, node.Pos())
+				}
+
+				nodes.Push(result)
+				break
+			}
+
+			// The left side of && or || was already
+			// popped, so the right side is the result
+			if op := node.(*Operator); op.typ == opTypeAnd || op.typ == opTypeOr {
+				if nodes.Length() < 1 {
+					return nil, errorAt(newParseError("Missing operand for %v", node), node.Pos())
+				}
+
+				result, err := interp.EvaluateNode(nodes.Pop())
+				if err != nil {
+					return nil, err
+				}
+
+				nodes.Push(result)
+				break
+			}
+
+			if nodes.Length() < 2 {
+				return nil, errorAt(newParseError("Missing operand for %v", node), node.Pos())
+			}
+
 			t1, t2 := nodes.Pop(), nodes.Pop()
-			nodes.Push(EvaluateNodes(t2, t1, node))
+			result, err := interp.EvaluateNodes(t2, t1, node)
+			if err != nil {
+				return nil, errorAt(err, node.Pos())
+			}
+
+			nodes.Push(result)
+		// If the left side of && or || decides the
+		// result, it is kept and the right side is
+		// skipped. Otherwise it is popped
+		case nodeTypeShortCircuit:
+			if nodes.Length() < 1 {
+				return nil, errorAt(newParseError("Missing operand for %v", node), node.Pos())
+			}
+
+			left, err := interp.EvaluateNode(nodes.Pop())
+			if err != nil {
+				return nil, err
+			}
+
+			op := node.(*ShortCircuit).op
+			if isTruthy(left) == (op.typ == opTypeOr) {
+				for ts.HasNext() {
+					if ts.Next() == Node(op) {
+						break
+					}
+				}
+
+				nodes.Push(left)
+			}
+		// If a list bracket is detected, pop its
+		// items off the stack into a new List
+		case nodeTypeBracket:
+			count := int(ts.Next().(ArgCount))
+			if nodes.Length() < count {
+				return nil, errorAt(newParseError("Missing item for %v", node), node.Pos())
+			}
+
+			values := make([]Node, count)
+			for i := count - 1; i >= 0; i-- {
+				value, err := interp.EvaluateNode(nodes.Pop())
+				if err != nil {
+					return nil, err
+				}
+
+				values[i] = value
+			}
+
+			list := NewList(values)
+			list.setPos(node.Pos())
+			nodes.Push(list)
+		// If a map brace is detected, pop its keys
+		// and values off the stack into a new Map
+		case nodeTypeBrace:
+			count := int(ts.Next().(ArgCount))
+			if nodes.Length() < 2*count {
+				return nil, errorAt(newParseError("Missing item for %v", node), node.Pos())
+			}
+
+			values := make([]Node, 2*count)
+			for i := 2*count - 1; i >= 0; i-- {
+				value, err := interp.EvaluateNode(nodes.Pop())
+				if err != nil {
+					return nil, err
+				}
+
+				values[i] = value
+			}
+
+			m := NewMap()
+			m.setPos(node.Pos())
+			for i := 0; i < len(values); i += 2 {
+				if err := m.Set(values[i], values[i+1]); err != nil {
+					return nil, errorAt(err, node.Pos())
+				}
+			}
+
+			nodes.Push(m)
 		// If a function call Node is detected, then
 		// pop nodes off the stack to pass to the
 		// function call until argCount is zero
 		case nodeTypeFuncCall:
 			argCount := ts.Next().(ArgCount)
 			args := NewNodeStream()
+			funcCall := node.(*FunctionCall)
+
+			// A call without a name calls the
+			// value below its arguments
+			count := int(argCount)
+			if funcCall.name == "" {
+				count++
+			}
+
+			if nodes.Length() < count {
+				return nil, errorAt(newParseError("Missing argument for %v", node), node.Pos())
+			}
 
 			for argCount > 0 {
-				args.Push(EvaluateNode(nodes.Pop()))
+				arg, err := interp.EvaluateNode(nodes.Pop())
+				if err != nil {
+					return nil, err
+				}
+
+				args.Push(arg)
 				argCount--
 			}
 
 			args.Reverse()
-			t := EvalulateFunctionCall(node, args)
+
+			var t Node
+			var err error
+			if funcCall.name == "" {
+				t, err = interp.evaluateValueCall(nodes.Pop(), funcCall, args)
+			} else {
+				t, err = interp.EvalulateFunctionCall(node, args)
+			}
+
+			if err != nil {
+				return nil, errorAt(err, node.Pos())
+			}
+
 			nodes.Push(t)
 		}
 	}
 
-	return EvaluateNode(nodes.Pop())
+	if nodes.Length() != 1 {
+		return nil, newParseError("Invalid expression %v", ts)
+	}
+
+	return interp.EvaluateNode(nodes.Pop())
 }
 
 // NewNodeStreamInRPN takes a nodeStream and rearranges
 // the nodes so they are in reverse polish notation
-func NewNodeStreamInRPN(ts *NodeStream) *NodeStream {
+func NewNodeStreamInRPN(ts *NodeStream) (*NodeStream, error) {
 	funcArgCounts := make(map[int]int, 0)
 	currFuncID := -1
 	ops, output := NewNodeStream(), NewNodeStream()
@@ -88,27 +358,189 @@ func NewNodeStreamInRPN(ts *NodeStream) *NodeStream {
 		node := ts.Next()
 		switch node.GetType() {
 		case nodeTypeNumber, nodeTypeBoolean,
-			nodeTypeVariable, nodeTypeString:
+			nodeTypeVariable, nodeTypeString, nodeTypeTemplate:
 			output.Push(node)
 		case nodeTypeFuncCall:
 			currFuncID++
 			funcArgCounts[currFuncID] = 1
 			ops.Push(node)
-		case nodeTypeComma:
+		case nodeTypeComma, nodeTypeColon:
+			// A comma outside of any group
+			// separates the values of a tuple
+			if node.GetType() == nodeTypeComma && !hasOpenGroup(ops) {
+				for {
+					op, ok := ops.Top().(*Operator)
+					if !ok || isAssignment(op) {
+						break
+					}
+
+					output.Push(ops.Pop())
+				}
+
+				if tuple, ok := ops.Top().(*Tuple); ok {
+					tuple.count++
+				} else {
+					tuple := &Tuple{count: 2}
+					tuple.setPos(node.Pos())
+					ops.Push(tuple)
+				}
+
+				break
+			}
+
+			if !popToGroup(ops, output) || !isSeparator(ops.Top(), node, funcArgCounts[currFuncID]) {
+				return nil, errorAt(newParseError("Unexpected %v in %v", node, ts), node.Pos())
+			}
+
 			funcArgCounts[currFuncID]++
-			for top := ops.Top(); !isLeftParen(top); top = ops.Top() {
-				output.Push(ops.Pop())
+			pushNamedArg(ts, ops)
+		case nodeTypeBracket:
+			bracket := node.(*Bracket)
+			if bracket.typ == parenTypeOpen {
+				// A list literal counts its items
+				// like the arguments of a call
+				if !bracket.index {
+					currFuncID++
+					funcArgCounts[currFuncID] = 1
+					if getGroupType(ts.Peek()) == parenTypeClose {
+						funcArgCounts[currFuncID] = 0
+					}
+				}
+
+				ops.Push(node)
+				break
+			}
+
+			if !popToGroup(ops, output) || ops.Top().GetType() != nodeTypeBracket {
+				return nil, errorAt(newParseError("Unmatched ] in %v", ts), node.Pos())
+			}
+
+			// An index is output as an operator on the
+			// collection and key, and a list literal
+			// as its opening bracket and item count
+			open := ops.Pop().(*Bracket)
+			if open.index {
+				index := &Operator{typ: opTypeIndex}
+				index.setPos(open.Pos())
+				output.Push(index)
+			} else {
+				output.Push(open)
+				output.Push(NewArgCount(funcArgCounts[currFuncID]))
+				currFuncID--
+			}
+		case nodeTypeBrace:
+			// A map literal counts its keys and
+			// values like the arguments of a call
+			if node.(*Brace).typ == parenTypeOpen {
+				currFuncID++
+				funcArgCounts[currFuncID] = 1
+				if getGroupType(ts.Peek()) == parenTypeClose {
+					funcArgCounts[currFuncID] = 0
+				}
+
+				ops.Push(node)
+				break
 			}
+
+			if !popToGroup(ops, output) || ops.Top().GetType() != nodeTypeBrace {
+				return nil, errorAt(newParseError("Unmatched } in %v", ts), node.Pos())
+			}
+
+			if funcArgCounts[currFuncID]%2 != 0 {
+				return nil, errorAt(newParseError("Expected key: value pairs in %v", ts), node.Pos())
+			}
+
+			// It is output as its opening
+			// brace and pair count
+			output.Push(ops.Pop())
+			output.Push(NewArgCount(funcArgCounts[currFuncID] / 2))
+			currFuncID--
 		case nodeTypeOperator:
+			// An operator without a value before it is
+			// a prefix, and `-` negates the value after
+			if isPrefix(ts) {
+				op := node.(*Operator)
+				if op.typ == opTypeSubtraction {
+					op = &Operator{typ: opTypeNegate}
+					op.setPos(node.Pos())
+				}
+
+				if isUnary(op) {
+					ops.Push(op)
+					break
+				}
+			}
+
 			for top := ops.Top(); top.GetType() == nodeTypeOperator; top = ops.Top() {
-				if shouldPopOperator(top, node) {
+				if shouldPopOperator(top.(*Operator), node.(*Operator)) {
 					output.Push(ops.Pop())
 				} else {
 					break
 				}
 			}
 
+			// The right side of && and || is skipped
+			// when the left side decides the result
+			if op := node.(*Operator); op.typ == opTypeAnd || op.typ == opTypeOr {
+				output.Push(NewShortCircuit(op))
+			}
+
+			// The targets of an assignment
+			// end at the `=`
+			if _, ok := ops.Top().(*Tuple); ok && isAssignment(node.(*Operator)) {
+				output.Push(ops.Pop())
+			}
+
 			ops.Push(node)
+		case nodeTypeReserved:
+			// A local declaration starts an expression and
+			// marks the variable after it, or each variable
+			// of a tuple, as in `local a, b = 1, 2`
+			if isReserved(node, "local") {
+				if _, ok := ts.Peek().(*Variable); !ok || output.Length() > 0 || ops.Length() > 0 {
+					return nil, errorAt(newParseError("Unexpected %v in %v", node, ts), node.Pos())
+				}
+
+				for i := ts.pos; i < ts.size; i += 2 {
+					v, ok := ts.nodes[i].(*Variable)
+					if !ok {
+						break
+					}
+
+					v.local = true
+					if i+1 >= ts.size || ts.nodes[i+1].GetType() != nodeTypeComma {
+						break
+					}
+				}
+
+				break
+			}
+
+			// An anonymous function is output as a value
+			if isReserved(node, "fn") {
+				ts.Backup()
+				lambda, err := ParseLambda(ts)
+				if err != nil {
+					return nil, err
+				}
+
+				output.Push(lambda)
+				break
+			}
+
+			// A one line if takes the rest of the
+			// expression and is output as a value
+			if !isReserved(node, "if") {
+				return nil, errorAt(newParseError("Unexpected %v in %v", node, ts), node.Pos())
+			}
+
+			ts.Backup()
+			oli, err := ParseOneLineIf(ts)
+			if err != nil {
+				return nil, err
+			}
+
+			output.Push(oli)
 		}
 
 		switch pType := getParenType(node); pType {
@@ -117,9 +549,11 @@ func NewNodeStreamInRPN(ts *NodeStream) *NodeStream {
 			if getParenType(ts.Peek()) == parenTypeClose {
 				funcArgCounts[currFuncID] = 0
 			}
+
+			pushNamedArg(ts, ops)
 		case parenTypeClose:
-			for top := ops.Top(); !isLeftParen(top); top = ops.Top() {
-				output.Push(ops.Pop())
+			if !popToGroup(ops, output) || !isLeftParen(ops.Top()) {
+				return nil, errorAt(newParseError("Unmatched ) in %v", ts), node.Pos())
 			}
 
 			ops.Pop()
@@ -132,21 +566,47 @@ func NewNodeStreamInRPN(ts *NodeStream) *NodeStream {
 	}
 
 	for ops.Length() > 0 {
+		if getGroupType(ops.Top()) == parenTypeOpen {
+			return nil, errorAt(newParseError("Unmatched %v in %v", ops.Top(), ts), ops.Top().Pos())
+		}
+
 		output.Push(ops.Pop())
 	}
 
-	return output
+	return output, nil
 }
 
 // EvaluateNodes performs an operation of two Nodes
-func EvaluateNodes(t1 Node, t2 Node, tokOp Node) Node {
+func (interp *Interpreter) EvaluateNodes(t1 Node, t2 Node, tokOp Node) (Node, error) {
+	var err error
 	opType := tokOp.(*Operator).typ
 
+	// A compound assignment applies its operator
+	// to the value of the target and assigns it
+	if op, ok := compoundOperators[opType]; ok {
+		value, err := interp.EvaluateNode(t1)
+		if err != nil {
+			return nil, err
+		}
+
+		operator := &Operator{typ: op}
+		operator.setPos(tokOp.Pos())
+		if value, err = interp.EvaluateNodes(value, t2, operator); err != nil {
+			return nil, err
+		}
+
+		return interp.AssignNode(t1, value)
+	}
+
 	if opType != opTypeAssignment {
-		t1 = EvaluateNode(t1)
+		if t1, err = interp.EvaluateNode(t1); err != nil {
+			return nil, err
+		}
 	}
 
-	t2 = EvaluateNode(t2)
+	if t2, err = interp.EvaluateNode(t2); err != nil {
+		return nil, err
+	}
 
 	switch opType {
 	case opTypeAddition:
@@ -162,86 +622,335 @@ func EvaluateNodes(t1 Node, t2 Node, tokOp Node) Node {
 	case opTypeModulus:
 		return ModNodes(t1, t2)
 	case opTypeAssignment:
-		return AssignNode(t1, t2)
+		return interp.AssignNode(t1, t2)
+	case opTypeIndex:
+		index := NewIndex(t1, t2)
+		index.setPos(tokOp.Pos())
+		return index, nil
 	case opTypeGreaterThan,
 		opTypeLessThan,
 		opTypeLessThanOrEqualTo,
 		opTypeGreaterThanOrEqualTo,
 		opTypeNotEqualTo,
-		opTypeEqualTo,
-		opTypeAnd,
-		opTypeOr:
+		opTypeEqualTo:
 		return CompareNodes(t1, t2, tokOp)
 	}
 
-	log.Fatalf("Could not %v on %v and %v", tokOp, t1, t2)
-	return &nodeNil{}
+	return nil, newRuntimeError("Could not %v on %v and %v", tokOp, t1, t2)
+}
+
+// EvaluateUnary performs a prefix operation on a Node
+func (interp *Interpreter) EvaluateUnary(t1 Node, tokOp Node) (Node, error) {
+	t1, err := interp.EvaluateNode(t1)
+	if err != nil {
+		return nil, err
+	}
+
+	switch tokOp.(*Operator).typ {
+	case opTypeNegate:
+		return NegateNode(t1)
+	case opTypeNot:
+		return NotNode(t1)
+	}
+
+	return nil, newRuntimeError("Could not %v on %v", tokOp, t1)
 }
 
-// EvaluateNode returns the value of a variable Node
-// or the Node if it's not a variable
-func EvaluateNode(t1 Node) Node {
-	if t1.GetType() == nodeTypeVariable {
-		var v Node
-		var err error
-		if v, err = GetVar(t1.(*Variable).name); err == nil {
-			return v
+// EvaluateNode returns the value of a variable or
+// index Node, or the Node if it's neither
+func (interp *Interpreter) EvaluateNode(t1 Node) (Node, error) {
+	switch t1.GetType() {
+	case nodeTypeVariable:
+		// A local declaration without
+		// a value declares it as nil
+		if t1.(*Variable).local {
+			interp.SetLocal(t1.(*Variable).name, &nodeNil{})
+			return &nodeNil{}, nil
+		}
+
+		name := t1.(*Variable).name
+		v, err := interp.GetVar(name)
+		if err != nil {
+			// Without a variable of the same
+			// name, a function is its value
+			if f, funcErr := interp.GetFunc(name); funcErr == nil {
+				return NewFunctionValue(name, f), nil
+			}
+
+			return nil, errorAt(err, t1.Pos())
+		}
+
+		return v, nil
+	case nodeTypeIndex:
+		index := t1.(*Index)
+		v, err := IndexNode(index.collection, index.key)
+		if err != nil {
+			return nil, errorAt(err, t1.Pos())
+		}
+
+		return v, nil
+	// A tuple's value is a List
+	// of the values it holds
+	case nodeTypeTuple:
+		tuple := t1.(*Tuple)
+		values := make([]Node, len(tuple.values))
+		for i, value := range tuple.values {
+			v, err := interp.EvaluateNode(value)
+			if err != nil {
+				return nil, err
+			}
+
+			values[i] = v
 		}
-		log.Fatal(err.Error())
+
+		list := NewList(values)
+		list.setPos(t1.Pos())
+		return list, nil
 	}
-	return t1
+
+	return t1, nil
 }
 
 // EvalulateFunctionCall runs the function stored in a function node
 // and returns the result
-func EvalulateFunctionCall(funcCall Node, args *NodeStream) Node {
-	f, err := GetFunc(funcCall.(*FunctionCall).name)
+func (interp *Interpreter) EvalulateFunctionCall(funcCall Node, args *NodeStream) (Node, error) {
+	name := funcCall.(*FunctionCall).name
+	f, err := interp.LookupFunc(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return interp.callFunction(name, f, funcCall.Pos(), args)
+}
+
+// evaluateValueCall runs the function that
+// `callee` evaluates to and returns the result
+func (interp *Interpreter) evaluateValueCall(callee Node, funcCall Node, args *NodeStream) (Node, error) {
+	callee, err := interp.EvaluateNode(callee)
+	if err != nil {
+		return nil, err
+	}
+
+	fv, ok := callee.(*FunctionValue)
+	if !ok {
+		return nil, newRuntimeError("Cannot call %v", callee)
+	}
+
+	return interp.callFunction(fv.name, fv.f, funcCall.Pos(), args)
+}
+
+// callFunction runs `f` with a Frame for the call
+// named `name` at `pos` on the call stack
+func (interp *Interpreter) callFunction(name string, f Function, pos Pos, args *NodeStream) (Node, error) {
+	if err := interp.pushFrame(name, pos, args); err != nil {
+		return nil, interp.traceError(errorAt(err, pos))
+	}
 
+	defer interp.popFrame()
+
+	result, err := f.Call(interp, args)
 	if err != nil {
-		log.Fatal(err.Error())
+		return nil, interp.traceError(errorAt(err, pos))
 	}
 
-	return f.Call(args)
+	return result, nil
 }
 
-// ParseOneLineIf parses a NodeStream into a `OneLineIf` struct
-func ParseOneLineIf(ns *NodeStream) *OneLineIf {
+// EvaluateTemplate evaluates each expression in
+// a Template and returns the String of its text
+// with their values formatted in it
+func (interp *Interpreter) EvaluateTemplate(t *Template) (*String, error) {
+	str := t.texts[0]
+
+	for i, expr := range t.exprs {
+		value, err := interp.EvaluateRPN(expr.Clone())
+		if err != nil {
+			return nil, err
+		}
+
+		valueStr, err := StringFromNode(value)
+		if err != nil {
+			return nil, err
+		}
+
+		str += valueStr + t.texts[i+1]
+	}
+
+	result := NewString(str)
+	result.setPos(t.Pos())
+	return result, nil
+}
+
+// EvaluateOneLineIf evaluates the condition of a
+// `OneLineIf` and then the branch it chooses
+func (interp *Interpreter) EvaluateOneLineIf(oli *OneLineIf) (Node, error) {
+	oli.cond.Reset()
+	condition, err := oli.cond.Evaluate(interp)
+	if err != nil {
+		return nil, err
+	}
+
+	branch := oli.passBlock
+	if !isTruthy(condition) {
+		branch = oli.elseBlock
+	}
+
+	if branch == nil {
+		return &nodeNil{}, nil
+	}
+
+	branch.Reset()
+	return branch.Evaluate(interp)
+}
+
+// ParseOneLineIf parses a NodeStream into a `OneLineIf` struct.
+// It reads from the `if` to the end of the expression, which
+// is the end of the NodeStream or a `,`, `)` or `]` that does
+// not belong to the `OneLineIf`
+func ParseOneLineIf(ns *NodeStream) (*OneLineIf, error) {
 	// if x == 1 then print(x) else print(x-1)
 	// if x == 2 then print(x+2)
 	oli := new(OneLineIf)
+	oli.setPos(ns.Next().Pos())
+	oli.cond = NewNodeStream()
+	part := oli.cond
+	parenDepth, nestedIfs := 0, 0
 
-	// Skip and remove the `if`
-	ns.Next()
-	ns = ns.Chop()
+	for ns.HasNext() {
+		node := ns.Next()
 
-	// Extract the condition
-	reserved := ns.Next()
-	for ns.HasNext() && reserved.(*Reserved).value != "then" {
-		reserved = ns.Next()
+		switch getGroupType(node) {
+		case parenTypeOpen:
+			parenDepth++
+		case parenTypeClose:
+			parenDepth--
+		}
+
+		if parenDepth < 0 || (parenDepth == 0 && node.GetType() == nodeTypeComma) {
+			ns.Backup()
+			break
+		}
+
+		if parenDepth == 0 {
+			switch {
+			case isReserved(node, "then") && part == oli.cond:
+				oli.passBlock = NewNodeStream()
+				part = oli.passBlock
+				continue
+			case isReserved(node, "if") && part != oli.cond:
+				nestedIfs++
+			// An else belongs to the nearest if
+			// that does not have one yet
+			case isReserved(node, "else") && part == oli.passBlock:
+				if nestedIfs == 0 {
+					oli.elseBlock = NewNodeStream()
+					part = oli.elseBlock
+					continue
+				}
+
+				nestedIfs--
+			}
+		}
+
+		part.Push(node)
+	}
+
+	switch {
+	case oli.passBlock == nil:
+		return nil, errorAt(newParseError("Expected then in one line if"), oli.Pos())
+	case oli.cond.Length() == 0:
+		return nil, errorAt(newParseError("Expected condition in one line if"), oli.Pos())
+	case oli.passBlock.Length() == 0:
+		return nil, errorAt(newParseError("Expected expression after then"), oli.Pos())
+	case oli.elseBlock != nil && oli.elseBlock.Length() == 0:
+		return nil, errorAt(newParseError("Expected expression after else"), oli.Pos())
 	}
 
-	ns.Next()
-	return oli
+	return oli, nil
+}
+
+// ParseLambda parses a NodeStream into a `Lambda`. It
+// reads from the `fn` to the `end` that matches it, and
+// converts the expression in between to RPN
+func ParseLambda(ns *NodeStream) (*Lambda, error) {
+	// fn(x) x * 2 end
+	// fn(a, b = 1) if a > b then a else b end
+	var err error
+	lambda := &Lambda{function: &UserFunction{name: "fn"}}
+	lambda.setPos(ns.Next().Pos())
+
+	if !isLeftParen(ns.Next()) {
+		return nil, errorAt(newParseError("Expected parameters after fn"), lambda.Pos())
+	}
+
+	if lambda.function.params, err = parseParams(ns); err != nil {
+		return nil, errorAt(err, lambda.Pos())
+	}
+
+	body := NewNodeStream()
+	depth := 1
+	for ns.HasNext() {
+		node := ns.Next()
+
+		switch {
+		case isReserved(node, "fn"):
+			depth++
+		case isReserved(node, "end"):
+			depth--
+		}
+
+		if depth == 0 {
+			break
+		}
+
+		body.Push(node)
+	}
+
+	switch {
+	case depth > 0:
+		return nil, errorAt(newParseError("Expected end after fn"), lambda.Pos())
+	case body.Length() == 0:
+		return nil, errorAt(newParseError("Expected expression in fn"), lambda.Pos())
+	}
+
+	if lambda.function.body, err = NewNodeStreamInRPN(body); err != nil {
+		return nil, err
+	}
+
+	return lambda, nil
 }
 
 // ParseForDeclaration parses a NodeStream into a `ForDeclaration`
-func ParseForDeclaration(ts *NodeStream) *ForDeclaration {
+func (interp *Interpreter) ParseForDeclaration(ts *NodeStream) (*ForDeclaration, error) {
 	// for 1 -> 20, counter, 2
 	// for 1 -> 20, counter
+	var err error
 	fd := new(ForDeclaration)
 
 	// Skip the "for"
 	ts.Next()
 
-	fd.start = Float64FromNode(ts.Next())
+	for _, node := range ts.nodes {
+		if isReserved(node, "in") {
+			return interp.parseForInDeclaration(ts, fd)
+		}
+	}
+
+	if fd.start, err = interp.nextFloat64(ts); err != nil {
+		return nil, err
+	}
+
 	arrowOp := ts.Next()
 
 	if arrowOp.GetType() != nodeTypeOperator ||
 		arrowOp.(*Operator).typ != opTypeArrow {
-		log.Fatal("Expected -> in for loop declaration")
+		return nil, errorAt(newParseError("Expected -> in for loop declaration"), arrowOp.Pos())
 	}
 
-	fd.end = Float64FromNode(ts.Next())
+	if fd.end, err = interp.nextFloat64(ts); err != nil {
+		return nil, err
+	}
 
 	// Skip comma
 	ts.Next()
@@ -250,7 +959,7 @@ func ParseForDeclaration(ts *NodeStream) *ForDeclaration {
 	if ts.HasNext() {
 		next := ts.Next()
 		if next.GetType() == nodeTypeNumber {
-			fd.step = Float64FromNode(next)
+			fd.step = next.(*Number).value
 		}
 
 		if next.GetType() == nodeTypeVariable {
@@ -258,26 +967,89 @@ func ParseForDeclaration(ts *NodeStream) *ForDeclaration {
 		}
 	}
 
+	if fd.counter == nil {
+		return nil, newParseError("Expected counter variable in for loop declaration")
+	}
+
 	// Skip comma
 	ts.Next()
 
 	if ts.HasNext() {
-		next := ts.Next()
-		if next.GetType() == nodeTypeVariable {
-			if scopeIsInitalized {
-				v, err := GetVar(next.(*Variable).name)
-				if err != nil {
-					fd.step = v.(*Number).value
-				}
-			}
+		if fd.step, err = interp.nextFloat64(ts); err != nil {
+			return nil, err
 		}
+	}
 
-		if next.GetType() == nodeTypeNumber {
-			fd.step = next.(*Number).value
+	return fd, nil
+}
+
+// parseForInDeclaration parses the rest of a for-in
+// loop declaration into `fd`, evaluating the collection
+func (interp *Interpreter) parseForInDeclaration(ts *NodeStream, fd *ForDeclaration) (*ForDeclaration, error) {
+	// for item in collection
+	// for key, value in collection
+	var ok bool
+
+	if fd.counter, ok = ts.Next().(*Variable); !ok {
+		return nil, newParseError("Expected variable in for loop declaration")
+	}
+
+	if ts.Peek().GetType() == nodeTypeComma {
+		ts.Next()
+		if fd.value, ok = ts.Next().(*Variable); !ok {
+			return nil, newParseError("Expected second variable in for loop declaration")
 		}
 	}
 
-	return fd
+	if in := ts.Next(); !isReserved(in, "in") {
+		return nil, errorAt(newParseError("Expected in after for loop variables"), in.Pos())
+	}
+
+	collection := ts.Chop()
+	if collection.Length() == 0 {
+		return nil, newParseError("Expected collection in for loop declaration")
+	}
+
+	var err error
+	if fd.collection, err = collection.Evaluate(interp); err != nil {
+		return nil, err
+	}
+
+	return fd, nil
+}
+
+// float64FromNode returns a float64 from a Node,
+// looking up the value of variables
+func (interp *Interpreter) float64FromNode(node Node) (float64, error) {
+	node, err := interp.EvaluateNode(node)
+	if err != nil {
+		return 0.0, err
+	}
+
+	return Float64FromNode(node)
+}
+
+// nextFloat64 returns the next Node in `ts` as a
+// float64. A negative number is a prefix `-` before
+// the number, so a `-` negates the Node after it
+func (interp *Interpreter) nextFloat64(ts *NodeStream) (float64, error) {
+	node := ts.Next()
+	negate := false
+	if op, ok := node.(*Operator); ok && op.typ == opTypeSubtraction {
+		negate = true
+		node = ts.Next()
+	}
+
+	num, err := interp.float64FromNode(node)
+	if err != nil {
+		return 0.0, err
+	}
+
+	if negate {
+		return -num, nil
+	}
+
+	return num, nil
 }
 
 // isLeftParen determines the node
@@ -299,23 +1071,137 @@ func getParenType(node Node) parenType {
 	return parenTypeNil
 }
 
-// shouldPopOperator is used in the conversion to RPN.
-// It is used when an operator is read and determines
-// if it should be popped based on the operator at the
-// top of the stack
-func shouldPopOperator(topNode Node, opNode Node) bool {
-	var ok bool
-	var topOp, op *Operator
+// getGroupType gets the paren type of a
+// node that is a paren, bracket or brace
+func getGroupType(node Node) parenType {
+	switch n := node.(type) {
+	case *Bracket:
+		return n.typ
+	case *Brace:
+		return n.typ
+	}
+
+	return getParenType(node)
+}
 
-	if topOp, ok = topNode.(*Operator); !ok {
-		log.Fatal("topNode is not an operator")
+// isIndexBracket determines if the node
+// is a bracket opening an index
+func isIndexBracket(node Node) bool {
+	if bracket, ok := node.(*Bracket); ok {
+		return bracket.index && bracket.typ == parenTypeOpen
 	}
 
-	if op, ok = opNode.(*Operator); !ok {
-		log.Fatal("topNode is not an operator")
+	return false
+}
+
+// isSeparator determines if `sep` can follow the
+// `count` items already in the group opened by
+// `group`. Map literals alternate `:` and `,`
+func isSeparator(group Node, sep Node, count int) bool {
+	switch {
+	case group.GetType() == nodeTypeBrace:
+		return (sep.GetType() == nodeTypeColon) == (count%2 == 1)
+	case sep.GetType() == nodeTypeColon, isIndexBracket(group):
+		return false
+	}
+
+	return true
+}
+
+// hasOpenGroup determines if there is a paren,
+// bracket or brace that opens a group in `ops`
+func hasOpenGroup(ops *NodeStream) bool {
+	for _, node := range ops.nodes {
+		if getGroupType(node) == parenTypeOpen {
+			return true
+		}
 	}
 
-	if op.typ == opTypeExponent {
+	return false
+}
+
+// popToGroup moves operators from `ops` to `output`
+// until a paren, bracket or brace that opens a group
+// is at the top, returning false if there is none
+func popToGroup(ops *NodeStream, output *NodeStream) bool {
+	for getGroupType(ops.Top()) != parenTypeOpen {
+		if ops.Length() == 0 {
+			return false
+		}
+
+		output.Push(ops.Pop())
+	}
+
+	return true
+}
+
+// pushNamedArg pushes a NamedArg onto `ops` if the
+// next nodes in `ts` are a name and `=` starting an
+// argument of the call whose paren is at the top
+func pushNamedArg(ts *NodeStream, ops *NodeStream) {
+	if !isLeftParen(ops.Top()) || ops.Length() < 2 ||
+		ops.nodes[ops.Length()-2].GetType() != nodeTypeFuncCall {
+		return
+	}
+
+	name, ok := ts.Peek().(*Variable)
+	if !ok || ts.pos+1 >= ts.Length() {
+		return
+	}
+
+	op, ok := ts.nodes[ts.pos+1].(*Operator)
+	if !ok || op.typ != opTypeAssignment {
+		return
+	}
+
+	ts.Next()
+	ts.Next()
+
+	// It stays below the operators of
+	// the value until the argument ends
+	arg := NewNamedArg(name.name, nil)
+	arg.setPos(name.Pos())
+	ops.Push(arg)
+}
+
+// isUnary determines if `op` is a
+// prefix operator with one operand
+func isUnary(op *Operator) bool {
+	return op.typ == opTypeNegate || op.typ == opTypeNot
+}
+
+// isPrefix determines if the node just read from
+// `ts` has no value before it in the expression
+func isPrefix(ts *NodeStream) bool {
+	if ts.pos < 2 {
+		return true
+	}
+
+	prev := ts.nodes[ts.pos-2]
+	switch prev.GetType() {
+	case nodeTypeOperator, nodeTypeComma, nodeTypeColon:
+		return true
+	case nodeTypeReserved:
+		return !isReserved(prev, "end")
+	}
+
+	return getGroupType(prev) == parenTypeOpen
+}
+
+// isAssignment determines if `op` is
+// an assignment or compound assignment
+func isAssignment(op *Operator) bool {
+	_, ok := compoundOperators[op.typ]
+	return ok || op.typ == opTypeAssignment
+}
+
+// shouldPopOperator is used in the conversion to RPN.
+// It is used when an operator is read and determines
+// if it should be popped based on the operator at the
+// top of the stack
+func shouldPopOperator(topOp *Operator, op *Operator) bool {
+	// Exponents and assignments are right associative
+	if op.typ == opTypeExponent || isAssignment(op) {
 		return opPrecedenceMap[op.typ] < opPrecedenceMap[topOp.typ]
 	}
 
@@ -1,49 +1,274 @@
 package blast
 
 import (
+	"bytes"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestRPN(t *testing.T) {
-	ts := NewNodeStreamFromLexer(Lex("212 + 341"))
-	rpn := NewNodeStreamInRPN(ts)
+	rpn := lexRPN(t, "212 + 341")
 
 	assert.Equal(t, nodeTypeNumber, rpn.Next().GetType())
 	assert.Equal(t, nodeTypeNumber, rpn.Next().GetType())
 	assert.Equal(t, nodeTypeOperator, rpn.Next().GetType())
 
-	ts = NewNodeStreamFromLexer(Lex("40 + (3 * 30.6)"))
-	rpn = NewNodeStreamInRPN(ts)
+	rpn = lexRPN(t, "40 + (3 * 30.6)")
 	assert.Equal(t, "40 3 30.6 * + ", rpn.String())
 
-	ts = NewNodeStreamFromLexer(Lex("27 * pi() + max(22, 33)"))
-	rpn = NewNodeStreamInRPN(ts)
+	rpn = lexRPN(t, "27 * pi() + max(22, 33)")
 	assert.Equal(t, "27 pi() 0 * 22 33 max() 2 + ", rpn.String())
 
-	ts = NewNodeStreamFromLexer(Lex("40 + max(4, 4+1, 88, 88) + 990"))
-	rpn = NewNodeStreamInRPN(ts)
+	rpn = lexRPN(t, "40 + max(4, 4+1, 88, 88) + 990")
 	assert.Equal(t, "40 4 4 1 + 88 88 max() 4 + 990 + ", rpn.String())
 
-	ts = NewNodeStreamFromLexer(Lex("max(min(33, 413), 300, 102)"))
-	rpn = NewNodeStreamInRPN(ts)
+	rpn = lexRPN(t, "max(min(33, 413), 300, 102)")
 	assert.Equal(t, "33 413 min() 2 300 102 max() 3 ", rpn.String())
+
+	rpn = lexRPN(t, "xs[i + 1] * [1, max(2, 3), []]")
+	assert.Equal(t, "xs i 1 + [] 1 2 3 max() 2 [ 0 [ 3 * ", rpn.String())
+
+	_, err := NewNodeStreamInRPN(lexNodeStream(t, "(1 + 2"))
+	assert.IsType(t, &ErrParse{}, err)
+
+	_, err = NewNodeStreamInRPN(lexNodeStream(t, "[1, (2]"))
+	assert.IsType(t, &ErrParse{}, err)
+
+	rpn = lexRPN(t, "{\"a\": 1 + 2, \"b\": {}}[\"a\"]")
+	assert.Equal(t, "\"a\" 1 2 + \"b\" { 0 { 2 \"a\" [] ", rpn.String())
+
+	rpn = lexRPN(t, "fs[0](1) + f(2)")
+	assert.Equal(t, "fs 0 [] 1 () 1 2 f() 1 + ", rpn.String())
+
+	rpn = lexRPN(t, "f(1, b = 2 * 3, c = (x = 4))")
+	assert.Equal(t, "1 2 3 * b = x 4 = c = f() 3 ", rpn.String())
+
+	rpn = lexRPN(t, "a, b = b + 1, f(a, 2)")
+	assert.Equal(t, "a b , 2 b 1 + a 2 f() 2 , 2 = ", rpn.String())
+
+	rpn = lexRPN(t, "-x ^ 2 + !a * -(b - 1)")
+	assert.Equal(t, "x 2 ^ - a ! b 1 - - * + ", rpn.String())
+
+	rpn = lexRPN(t, "a && b == 1 || f(c || d)")
+	assert.Equal(t, "a &&? b 1 == && ||? c ||? d || f() 1 || ", rpn.String())
+
+	rpn = lexRPN(t, "a = b += c ^= 2 * 3")
+	assert.Equal(t, "a b c 2 3 * ^= += = ", rpn.String())
+
+	_, err = NewNodeStreamInRPN(lexNodeStream(t, "{1: 2]"))
+	assert.IsType(t, &ErrParse{}, err)
+
+	_, err = NewNodeStreamInRPN(lexNodeStream(t, "1 + 2)"))
+	assert.IsType(t, &ErrParse{}, err)
 }
 
 func TestRPNEvaluation(t *testing.T) {
-	ts := NewNodeStreamFromLexer(Lex("212 + 341"))
-	rpn := NewNodeStreamInRPN(ts)
+	rpn := lexRPN(t, "212 + 341")
+
+	interp := NewInterpreter()
+	result, err := interp.EvaluateRPN(rpn)
+	assert.Nil(t, err)
+	assertFloat64FromNode(t, 553, result)
+
+	_, err = interp.EvaluateRPN(lexRPN(t, "212 +"))
+	assert.IsType(t, &ErrParse{}, err)
+}
+
+func TestUnaryOperators(t *testing.T) {
+	interp := NewInterpreter()
+
+	tests := []struct {
+		code   string
+		result string
+	}{
+		{"x = 4\n-x", "-4"},
+		{"a = 1\nb = 2\n-(a + b)", "-3"},
+		{"5-3", "2"},
+		{"x -1", "3"},
+		{"[x -1, 5 -.5]", "[3, 4.5]"},
+		{"-x^2", "-16"},
+		{"- -x", "4"},
+		{"[1, -x, 3 - -x]", "[1, -4, 7]"},
+		{"done = false\n!done", "true"},
+		{"not done && x > 3", "true"},
+		{"!(x > 3)", "false"},
+		{"!!true", "true"},
+		{"[!\"a\", !0, ![], !\"\"]", "[false, true, false, false]"},
+		{"y = -x\ny", "-4"},
+		{"y=-x\ny", "-4"},
+		{"[2*-x, x==-4, 2^-1, x+-1, x--1, true&&!false]", "[-8, false, 0.5, 3, 5, true]"},
+	}
+
+	for _, test := range tests {
+		result, err := interp.Run(test.code)
+		assert.Nil(t, err)
+		assert.Equal(t, test.result, result.String(), test.code)
+	}
+
+	_, err := interp.Run("-\"a\"")
+	assert.EqualError(t, err, "1:1: Cannot negate \"a\"")
+
+	_, err = interp.Run("1 ! 2")
+	assert.IsType(t, &ErrParse{}, err)
+}
+
+func TestShortCircuit(t *testing.T) {
+	interp := NewInterpreter()
+
+	_, err := interp.Run(strings.Join([]string{
+		"calls = 0",
+		"function check(value)",
+		"  calls += 1",
+		"  return value",
+		"end",
+	}, "\n"))
+	assert.Nil(t, err)
+
+	tests := []struct {
+		code   string
+		result string
+		calls  string
+	}{
+		{"false && check(true)", "false", "0"},
+		{"true || check(false)", "true", "0"},
+		{"true && check(false)", "false", "1"},
+		{"false || check(true)", "true", "2"},
+		{"x = 0\nx != 0 && check(10 / x > 1)", "false", "2"},
+		{"1 && \"a\"", "\"a\"", "2"},
+		{"0 || \"b\"", "\"b\"", "2"},
+		{"local input\ninput || \"default\"", "\"default\"", "2"},
+		{"[] || check(1)", "[]", "2"},
+		{"false || false && check(true) || 3", "3", "2"},
+	}
 
-	result := EvaluateRPN(rpn)
+	for _, test := range tests {
+		result, err := interp.Run(test.code)
+		assert.Nil(t, err)
+		assert.Equal(t, test.result, result.String(), test.code)
 
-	assert.Equal(t, 553, Float64FromNode(result))
+		calls, err := interp.Run("calls")
+		assert.Nil(t, err)
+		assert.Equal(t, test.calls, calls.String(), test.code)
+	}
+}
+
+func TestCompoundAssignment(t *testing.T) {
+	interp := NewInterpreter()
+
+	tests := []struct {
+		code   string
+		result string
+	}{
+		{"count = 1\ncount += 2\ncount", "3"},
+		{"count -= 4", "-1"},
+		{"count *= -6", "6"},
+		{"count /= 3", "2"},
+		{"count %= 3", "2"},
+		{"count ^= 3", "8"},
+		{"s = \"a\"\ns += \"b\"\ns", "\"ab\""},
+		{"xs = [1, 2]\nxs[1] *= 10\nxs", "[1, 20]"},
+		{"a = 1\nb = 2\na += b += 3\n[a, b]", "[6, 5]"},
+	}
+
+	for _, test := range tests {
+		result, err := interp.Run(test.code)
+		assert.Nil(t, err)
+		assert.Equal(t, test.result, result.String(), test.code)
+	}
+
+	_, err := interp.Run("missing += 1")
+	assert.EqualError(t, err, "1:1: Variable missing not found")
 }
 
 func TestForLoopParsing(t *testing.T) {
-	fd := ParseForDeclaration(NewNodeStreamFromLexer(Lex("for 1 -> 20, x, 1")))
+	interp := NewInterpreter()
+	fd, err := interp.ParseForDeclaration(lexNodeStream(t, "for 1 -> 20, x, 1"))
+	assert.Nil(t, err)
 	assert.Equal(t, 1.0, fd.start)
-	assert.Equal(t, 20, fd.end)
+	assert.Equal(t, 20.0, fd.end)
 	assert.Equal(t, "x", fd.counter.name)
 	assert.Equal(t, 1.0, fd.step)
+
+	fd, err = interp.ParseForDeclaration(lexNodeStream(t, "for -2 -> -8, x, -2"))
+	assert.Nil(t, err)
+	assert.Equal(t, -2.0, fd.start)
+	assert.Equal(t, -8.0, fd.end)
+	assert.Equal(t, -2.0, fd.step)
+
+	result, err := interp.Run("xs = []\nfor -2 -> 0, i\n  push(xs, i)\nend\nfor 3 -> 1, j, -1\n  push(xs, j)\nend\nxs")
+	assert.Nil(t, err)
+	assert.Equal(t, "[-2, -1, 0, 3, 2, 1]", result.String())
+
+	_, err = interp.ParseForDeclaration(lexNodeStream(t, "for 1 20, x"))
+	assert.IsType(t, &ErrParse{}, err)
+
+	fd, err = interp.ParseForDeclaration(lexNodeStream(t, "for key, value in {\"a\": 1}"))
+	assert.Nil(t, err)
+	assert.Equal(t, "key", fd.counter.name)
+	assert.Equal(t, "value", fd.value.name)
+	assert.Equal(t, "{\"a\": 1}", fd.collection.String())
+
+	_, err = interp.ParseForDeclaration(lexNodeStream(t, "for x y in [1]"))
+	assert.IsType(t, &ErrParse{}, err)
+
+	_, err = interp.ParseForDeclaration(lexNodeStream(t, "for x in"))
+	assert.IsType(t, &ErrParse{}, err)
+}
+
+func lexRPN(t *testing.T, code string) *NodeStream {
+	rpn, err := NewNodeStreamInRPN(lexNodeStream(t, code))
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	return rpn
+}
+
+func TestParseLambda(t *testing.T) {
+	ns := lexNodeStream(t, "fn(a, b = 2) fn(c) a * c end(b) end + 1")
+	lambda, err := ParseLambda(ns)
+	assert.Nil(t, err)
+	assert.Equal(t, "fn(a, b) fn(c) a c * end b () 1 end", lambda.String())
+	assert.Equal(t, "+ 1 ", ns.Chop().String())
+
+	_, err = ParseLambda(lexNodeStream(t, "fn(a a end"))
+	assert.IsType(t, &ErrParse{}, err)
+}
+
+func TestOneLineIf(t *testing.T) {
+	oli, err := ParseOneLineIf(lexNodeStream(t, "if x == 1 then print(x) else print(x - 1)"))
+	assert.Nil(t, err)
+	assert.Equal(t, "x == 1 ", oli.cond.String())
+	assert.Equal(t, "print() ( x ) ", oli.passBlock.String())
+	assert.Equal(t, "print() ( x - 1 ) ", oli.elseBlock.String())
+
+	_, err = ParseOneLineIf(lexNodeStream(t, "if x == 1 print(x)"))
+	assert.IsType(t, &ErrParse{}, err)
+
+	_, err = ParseOneLineIf(lexNodeStream(t, "if x == 1 then print(x) else"))
+	assert.IsType(t, &ErrParse{}, err)
+
+	var out bytes.Buffer
+	interp := NewInterpreter()
+	interp.SetOutput(&out)
+
+	result, err := interp.Run(strings.Join([]string{
+		"x = 2",
+		"if x == 1 then print(x) else print(x - 1)",
+		"if x == 2 then print(x + 2)",
+		"if x == 3 then print(x + 3)",
+		"y = if x > 1 then \"big\" else \"small\"",
+		"y = if y then y else \"none\"",
+		"z = max(if x > 1 then if x > 5 then 3 else 2 else 1, 0)",
+		"function max(a, b)",
+		"  return if a > b then a else b",
+		"end",
+		"y + z",
+	}, "\n"))
+	assert.Nil(t, err)
+	assertStringFromNode(t, "big2", result)
+	assert.Equal(t, "14", out.String())
 }
@@ -1,24 +1,8 @@
 package blast
 
-import (
-	"fmt"
-	"io/ioutil"
-	"strings"
-)
-
-func RunFile(fName string) {
-
-	if !strings.HasSuffix(fName, ".blast") {
-		fName += ".blast"
-	}
-
-	data, err := ioutil.ReadFile(fName)
-
-	if err != nil {
-		fmt.Errorf("%s", err)
-	}
-
-	InitScope()
-	block := ParseCode(string(data))
-	block.RunBlocks()
+// RunFile runs the blast code in the file `fName`
+// in a new Interpreter and returns any error
+// it produces
+func RunFile(fName string) error {
+	return NewInterpreter().RunFile(fName)
 }
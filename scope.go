@@ -2,17 +2,15 @@ package blast
 
 import "fmt"
 
-var (
-	// Global scope stack
-	Scopes            ScopeStack
-	scopeIsInitalized = false
-)
-
-// Scope stores a map of Nodes
-// and a map of Functions
+// Scope stores a map of Nodes and a map of
+// Functions, and the Scope it is nested in.
+// A function Scope is where variables are
+// set when they are not declared anywhere
 type Scope struct {
-	vars  map[string]Node
-	funcs map[string]Function
+	vars     map[string]Node
+	funcs    map[string]Function
+	parent   *Scope
+	function bool
 }
 
 // ScopeStack is a stack
@@ -22,45 +20,91 @@ type ScopeStack struct {
 	size   int
 }
 
-// InitScope initalizes the global ScopeStack
-// and loads the builtin functions
-func InitScope() {
-	Scopes.scopes = append(Scopes.scopes, NewScope())
-	Scopes.size = 1
-	scopeIsInitalized = true
-	LoadBuiltinFunctions()
-}
-
 // GlobalScope returns the scope
 // at the bottom of the scope stack
-func GlobalScope() *Scope {
-	return Scopes.scopes[0]
+func (interp *Interpreter) GlobalScope() *Scope {
+	return interp.scopes.scopes[0]
 }
 
-// GlobalScope returns the scope
+// CurrScope returns the scope
 // at the top of the scope stack
-func CurrScope() *Scope {
-	return Scopes.Top()
+func (interp *Interpreter) CurrScope() *Scope {
+	return interp.scopes.Top()
 }
 
-// SetVar sets a variable on the current scope
-func SetVar(name string, node Node) {
-	Scopes.scopes[Scopes.size-1].SetVar(name, node)
+// SetVar sets a variable on the scope it is declared
+// on, or else on the scope of the current function
+// or the global scope
+func (interp *Interpreter) SetVar(name string, node Node) {
+	if owner := interp.CurrScope().owner(name); owner != nil {
+		owner.SetVar(name, node)
+		return
+	}
+
+	interp.functionScope().SetVar(name, node)
 }
 
-// GetVar gets a variable from the current scope
-func GetVar(name string) (Node, error) {
-	return Scopes.scopes[Scopes.size-1].GetVar(name)
+// functionScope returns the scope of the
+// current function or the global scope
+func (interp *Interpreter) functionScope() *Scope {
+	s := interp.CurrScope()
+	for !s.function && s.parent != nil {
+		s = s.parent
+	}
+
+	return s
 }
 
-// GetFunc returns a function from the current scope
-func GetFunc(name string) (Function, error) {
-	return Scopes.scopes[0].GetFunc(name)
+// SetLocal declares a variable
+// on the current scope
+func (interp *Interpreter) SetLocal(name string, node Node) {
+	interp.CurrScope().SetVar(name, node)
 }
 
-// SetFunc sets a function on the current scope
-func SetFunc(name string, f Function) {
-	Scopes.scopes[0].SetFunc(name, f)
+// GetVar gets a variable from the current
+// scope or the scopes it is nested in
+func (interp *Interpreter) GetVar(name string) (Node, error) {
+	return interp.CurrScope().GetVar(name)
+}
+
+// GetFunc returns a function from the current
+// scope or the scopes it is nested in, or a
+// builtin function
+func (interp *Interpreter) GetFunc(name string) (Function, error) {
+	f, err := interp.CurrScope().GetFunc(name)
+	if err == nil {
+		return f, nil
+	}
+
+	if f, ok := interp.builtins[name]; ok {
+		return f, nil
+	}
+
+	return f, err
+}
+
+// LookupFunc returns the function stored in the
+// variable `name`, or else the function declared
+// or built in as `name`
+func (interp *Interpreter) LookupFunc(name string) (Function, error) {
+	v, err := interp.GetVar(name)
+	if err != nil {
+		return interp.GetFunc(name)
+	}
+
+	if fv, ok := v.(*FunctionValue); ok {
+		return fv.f, nil
+	}
+
+	return nil, newRuntimeError("%s is %v, not a function", name, v)
+}
+
+// SetFunc sets a function on the scope of
+// the current function or the global scope,
+// so a function declared in another function
+// can only be called from inside it
+func (interp *Interpreter) SetFunc(name string, f Function) {
+	interp.functionScope().SetFunc(name, f)
 }
 
 // ErrVarNotFound is thrown when a
@@ -103,18 +147,34 @@ func (s *Scope) SetVar(name string, value Node) {
 }
 
 // GetVar gets a variable from the Scope
+// or the Scopes it is nested in
 func (s *Scope) GetVar(name string) (Node, error) {
-	if v, ok := s.vars[name]; ok {
-		return v, nil
+	if owner := s.owner(name); owner != nil {
+		return owner.vars[name], nil
 	}
 
 	return &nodeNil{}, &ErrVarNotFound{name}
 }
 
-// GetFunc returns the function from the Scope
+// owner returns the nearest Scope that
+// declares the variable `name`, or nil
+func (s *Scope) owner(name string) *Scope {
+	for ; s != nil; s = s.parent {
+		if _, ok := s.vars[name]; ok {
+			return s
+		}
+	}
+
+	return nil
+}
+
+// GetFunc returns the function from the
+// Scope or the Scopes it is nested in
 func (s *Scope) GetFunc(name string) (Function, error) {
-	if f, ok := s.funcs[name]; ok {
-		return f, nil
+	for ; s != nil; s = s.parent {
+		if f, ok := s.funcs[name]; ok {
+			return f, nil
+		}
 	}
 
 	return funcNil, &ErrFuncNotFound{name}
@@ -149,33 +209,47 @@ func (st *ScopeStack) Top() *Scope {
 	return st.scopes[st.size-1]
 }
 
-// New adds a new Scope the to the scope stack
+// New adds a new Scope nested in the
+// current Scope to the scope stack
 func (st *ScopeStack) New() *Scope {
-	s := new(Scope)
-	s.vars = st.Top().vars
-	s.funcs = st.Top().funcs
+	s := NewScope()
+	s.parent = st.Top()
+	return st.push(s)
+}
 
+// NewFunction adds a new function Scope nested
+// in `parent` to the scope stack, so the body of
+// a function can't see the variables of its caller
+func (st *ScopeStack) NewFunction(parent *Scope) *Scope {
+	s := NewScope()
+	s.parent = parent
+	s.function = true
+	return st.push(s)
+}
+
+// push adds `s` to the top of the stack
+func (st *ScopeStack) push(s *Scope) *Scope {
 	st.size++
 	st.scopes = append(st.scopes, s)
 	return s
 }
 
 // Pop removes the Scope at the top
-// of the stack
+// of the stack. Its variables go
+// with it
 func (st *ScopeStack) Pop() *Scope {
 	top := st.Top()
 	st.size--
-	curr := st.Top()
-
-	// Move any variables that could
-	// have been modified to the
-	// new current Scope
-	for name, node := range top.vars {
-		if _, ok := curr.vars[name]; ok {
-			curr.vars[name] = node
-		}
-	}
-
 	st.scopes = st.scopes[:st.size]
 	return top
 }
+
+// truncate removes Scopes from the top of the
+// stack until only `size` are left, without
+// moving any variables
+func (st *ScopeStack) truncate(size int) {
+	if size < st.size {
+		st.size = size
+		st.scopes = st.scopes[:size]
+	}
+}
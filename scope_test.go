@@ -1,26 +1,27 @@
 package blast
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestGlobalScope(t *testing.T) {
-	InitScope()
+	interp := NewInterpreter()
 
-	assert.NotNil(t, GlobalScope())
-	assert.NotNil(t, CurrScope())
+	assert.NotNil(t, interp.GlobalScope())
+	assert.NotNil(t, interp.CurrScope())
 }
 
 func TestVariables(t *testing.T) {
-	InitScope()
+	interp := NewInterpreter()
 
-	SetVar("x", NewNumber("200"))
-	SetVar("y", NewNumber("300"))
+	interp.SetVar("x", NewNumberFromFloat(200))
+	interp.SetVar("y", NewNumberFromFloat(300))
 
-	x, errX := GetVar("x")
-	y, errY := GetVar("y")
+	x, errX := interp.GetVar("x")
+	y, errY := interp.GetVar("y")
 
 	assert.Nil(t, errX)
 	assert.Nil(t, errY)
@@ -28,3 +29,94 @@ func TestVariables(t *testing.T) {
 	assert.Equal(t, "200", x.String())
 	assert.Equal(t, "300", y.String())
 }
+
+func TestNestedScopes(t *testing.T) {
+	interp := NewInterpreter()
+	interp.SetVar("x", NewNumberFromFloat(1))
+
+	interp.scopes.New()
+	interp.SetLocal("x", NewNumberFromFloat(2))
+	interp.SetVar("y", NewNumberFromFloat(3))
+
+	x, err := interp.GetVar("x")
+	assert.Nil(t, err)
+	assert.Equal(t, "2", x.String())
+
+	interp.scopes.Pop()
+
+	// Undeclared variables are set on the
+	// function or global scope
+	x, _ = interp.GetVar("x")
+	y, err := interp.GetVar("y")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", x.String())
+	assert.Equal(t, "3", y.String())
+
+	// A function scope can only see the
+	// scope it is nested in
+	interp.scopes.New()
+	interp.SetLocal("z", NewNumberFromFloat(4))
+	interp.scopes.NewFunction(interp.GlobalScope())
+
+	_, err = interp.GetVar("z")
+	assert.IsType(t, &ErrVarNotFound{}, err)
+
+	interp.SetVar("x", NewNumberFromFloat(5))
+	interp.scopes.truncate(1)
+
+	x, _ = interp.GetVar("x")
+	assert.Equal(t, "5", x.String())
+}
+
+func TestLocalDeclarations(t *testing.T) {
+	interp := NewInterpreter()
+
+	result, err := interp.Run(strings.Join([]string{
+		"x = 1",
+		"function clobber()",
+		"  x = 2",
+		"  count = 10",
+		"end",
+		"function caller()",
+		"  local x = 5",
+		"  count = 1",
+		"  clobber()",
+		"  if true",
+		"    local count = 20",
+		"  end",
+		"  return [x, count]",
+		"end",
+		"[caller(), x]",
+	}, "\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, "[[5, 1], 2]", result.String())
+	assert.Equal(t, 1, interp.scopes.size)
+
+	_, err = interp.Run("count")
+	assert.IsType(t, &ErrRuntime{}, err)
+
+	result, err = interp.Run("local y\ny")
+	assert.Nil(t, err)
+	assert.Equal(t, "<NIL>", result.String())
+
+	_, err = interp.Run("y = local z")
+	assert.IsType(t, &ErrParse{}, err)
+
+	// Every variable of a tuple is local
+	result, err = interp.Run("if true\n  local a, b = 1, 2\n  c = a + b\nend\nc")
+	assert.Nil(t, err)
+	assertFloat64FromNode(t, 3, result)
+
+	_, err = interp.Run("a")
+	assert.IsType(t, &ErrRuntime{}, err)
+
+	_, err = interp.Run("b")
+	assert.IsType(t, &ErrRuntime{}, err)
+
+	_, err = interp.Run("if true\n  local d, e\n  d = 4\nend\nd")
+	assert.IsType(t, &ErrRuntime{}, err)
+
+	// Loop counters are local to the loop
+	_, err = interp.Run("for 1 -> 3, i\nend\ni")
+	assert.EqualError(t, err, "3:1: Variable i not found")
+}
@@ -0,0 +1,292 @@
+package blast
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// stringArg returns the value of a String
+// passed to a builtin. Unlike StringFromNode,
+// other values are not converted
+func stringArg(node Node) (string, error) {
+	if str, ok := node.(*String); ok {
+		return str.value, nil
+	}
+
+	return "", newRuntimeError("Expected a string, got %v", node)
+}
+
+// stringArgs returns the values of the Strings
+// passed to the builtin `name`, checking that
+// there are exactly `count` of them
+func stringArgs(name string, args *NodeStream, count int) ([]string, error) {
+	if err := checkArgCount(name, args, count, count); err != nil {
+		return nil, err
+	}
+
+	strs := make([]string, count)
+	for i, arg := range args.nodes {
+		str, err := stringArg(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		strs[i] = str
+	}
+
+	return strs, nil
+}
+
+// builtinUpper returns a String in upper case
+func builtinUpper(interp *Interpreter, args *NodeStream) (interface{}, error) {
+	strs, err := stringArgs("upper", args, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.ToUpper(strs[0]), nil
+}
+
+// builtinLower returns a String in lower case
+func builtinLower(interp *Interpreter, args *NodeStream) (interface{}, error) {
+	strs, err := stringArgs("lower", args, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.ToLower(strs[0]), nil
+}
+
+// builtinTrim returns a String without the
+// whitespace at either end, or without the
+// characters in `chars` if it is passed
+func builtinTrim(interp *Interpreter, args *NodeStream) (interface{}, error) {
+	if err := checkArgCount("trim", args, 1, 2); err != nil {
+		return nil, err
+	}
+
+	strs, err := stringArgs("trim", args, args.Length())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(strs) == 2 {
+		return strings.Trim(strs[0], strs[1]), nil
+	}
+
+	return strings.TrimSpace(strs[0]), nil
+}
+
+// builtinSplit returns a List of the parts of a
+// String between each `sep`. Without a `sep`,
+// it is split on whitespace
+func builtinSplit(interp *Interpreter, args *NodeStream) (interface{}, error) {
+	if err := checkArgCount("split", args, 1, 2); err != nil {
+		return nil, err
+	}
+
+	strs, err := stringArgs("split", args, args.Length())
+	if err != nil {
+		return nil, err
+	}
+
+	var parts []string
+	if len(strs) == 2 {
+		parts = strings.Split(strs[0], strs[1])
+	} else {
+		parts = strings.Fields(strs[0])
+	}
+
+	values := make([]Node, len(parts))
+	for i, part := range parts {
+		values[i] = NewString(part)
+	}
+
+	return NewList(values), nil
+}
+
+// builtinJoin returns the items of a List as
+// one String with `sep` between them
+func builtinJoin(interp *Interpreter, args *NodeStream) (interface{}, error) {
+	if err := checkArgCount("join", args, 1, 2); err != nil {
+		return nil, err
+	}
+
+	list, err := ListFromNode(args.nodes[0])
+	if err != nil {
+		return nil, err
+	}
+
+	sep := ""
+	if args.Length() == 2 {
+		if sep, err = stringArg(args.nodes[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	strs := make([]string, len(list.values))
+	for i, value := range list.values {
+		if strs[i], err = StringFromNode(value); err != nil {
+			return nil, err
+		}
+	}
+
+	return strings.Join(strs, sep), nil
+}
+
+// builtinReplace returns a String with
+// every `old` replaced by `new`
+func builtinReplace(interp *Interpreter, args *NodeStream) (interface{}, error) {
+	strs, err := stringArgs("replace", args, 3)
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.Replace(strs[0], strs[1], strs[2], -1), nil
+}
+
+// builtinContains determines if a
+// String contains `sub`
+func builtinContains(interp *Interpreter, args *NodeStream) (interface{}, error) {
+	strs, err := stringArgs("contains", args, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.Contains(strs[0], strs[1]), nil
+}
+
+// builtinStartsWith determines if
+// a String starts with `prefix`
+func builtinStartsWith(interp *Interpreter, args *NodeStream) (interface{}, error) {
+	strs, err := stringArgs("starts_with", args, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.HasPrefix(strs[0], strs[1]), nil
+}
+
+// builtinEndsWith determines if
+// a String ends with `suffix`
+func builtinEndsWith(interp *Interpreter, args *NodeStream) (interface{}, error) {
+	strs, err := stringArgs("ends_with", args, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.HasSuffix(strs[0], strs[1]), nil
+}
+
+// builtinIndexOf returns the index of the first
+// character of `sub` in a String, counted in
+// characters, or -1 if it is not found
+func builtinIndexOf(interp *Interpreter, args *NodeStream) (interface{}, error) {
+	strs, err := stringArgs("index_of", args, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	i := strings.Index(strs[0], strs[1])
+	if i == -1 {
+		return -1, nil
+	}
+
+	return utf8.RuneCountInString(strs[0][:i]), nil
+}
+
+// builtinSubstring returns the characters of a
+// String from `start` up to but not including
+// `end`, which defaults to the length of it
+func builtinSubstring(interp *Interpreter, args *NodeStream) (interface{}, error) {
+	if err := checkArgCount("substring", args, 2, 3); err != nil {
+		return nil, err
+	}
+
+	str, err := stringArg(args.nodes[0])
+	if err != nil {
+		return nil, err
+	}
+
+	runes := []rune(str)
+	start, err := IntFromNode(args.nodes[1])
+	if err != nil {
+		return nil, err
+	}
+
+	end := len(runes)
+	if args.Length() == 3 {
+		if end, err = IntFromNode(args.nodes[2]); err != nil {
+			return nil, err
+		}
+	}
+
+	if start < 0 || end > len(runes) || start > end {
+		return nil, newRuntimeError("Substring [%d:%d] out of range for string of length %d", start, end, len(runes))
+	}
+
+	return string(runes[start:end]), nil
+}
+
+// builtinRepeat returns a String
+// repeated `count` times
+func builtinRepeat(interp *Interpreter, args *NodeStream) (interface{}, error) {
+	if err := checkArgCount("repeat", args, 2, 2); err != nil {
+		return nil, err
+	}
+
+	str, err := stringArg(args.nodes[0])
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := IntFromNode(args.nodes[1])
+	if err != nil {
+		return nil, err
+	}
+
+	if count < 0 {
+		return nil, newRuntimeError("Cannot repeat a string %d times", count)
+	}
+
+	if err := checkRepeatLength(str, float64(count)); err != nil {
+		return nil, err
+	}
+
+	return strings.Repeat(str, count), nil
+}
+
+// builtinChar returns the character
+// with the unicode code point `code`
+func builtinChar(interp *Interpreter, args *NodeStream) (interface{}, error) {
+	if err := checkArgCount("char", args, 1, 1); err != nil {
+		return nil, err
+	}
+
+	code, err := IntFromNode(args.nodes[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if !utf8.ValidRune(rune(code)) || code != int(rune(code)) {
+		return nil, newRuntimeError("Invalid character code %d", code)
+	}
+
+	return string(rune(code)), nil
+}
+
+// builtinOrd returns the unicode code
+// point of a one character String
+func builtinOrd(interp *Interpreter, args *NodeStream) (interface{}, error) {
+	strs, err := stringArgs("ord", args, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	r, size := utf8.DecodeRuneInString(strs[0])
+	if size == 0 || size != len(strs[0]) {
+		return nil, newRuntimeError("ord expects a single character, got %v", args.nodes[0])
+	}
+
+	return int(r), nil
+}
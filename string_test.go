@@ -0,0 +1,62 @@
+package blast
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStringBuiltins(t *testing.T) {
+	interp := NewInterpreter()
+
+	tests := []struct {
+		code   string
+		result string
+	}{
+		{`upper("héllo")`, `"HÉLLO"`},
+		{`lower("ÀB c")`, `"àb c"`},
+		{`trim("  a b \n")`, `"a b"`},
+		{`trim("--a-b--", "-")`, `"a-b"`},
+		{`split("a,b,,c", ",")`, `["a", "b", "", "c"]`},
+		{`split(" one  two ")`, `["one", "two"]`},
+		{`split("añb", "")`, `["a", "ñ", "b"]`},
+		{`join(["a", 1, true], ", ")`, `"a, 1, true"`},
+		{`join(split("a b", " "))`, `"ab"`},
+		{`replace("a-b-c", "-", "+")`, `"a+b+c"`},
+		{`[contains("héllo", "él"), starts_with("héllo", "hé"), ends_with("héllo", "x")]`, `[true, true, false]`},
+		{`[index_of("héllo", "l"), index_of("héllo", "z")]`, `[2, -1]`},
+		{`[substring("héllo", 1, 3), substring("héllo", 3)]`, `["él", "lo"]`},
+		{`repeat("ab", 3)`, `"ababab"`},
+		{`[char(233), ord("é"), char(ord("a") + 1)]`, `["é", 233, "b"]`},
+		{`len("héllo")`, `5`},
+	}
+
+	for _, test := range tests {
+		result, err := interp.Run(test.code)
+		assert.Nil(t, err, test.code)
+		assert.Equal(t, test.result, result.String(), test.code)
+	}
+
+	errTests := []struct {
+		code string
+		err  string
+	}{
+		{`upper(1)`, "1:1: Expected a string, got 1"},
+		{`upper("a", "b")`, "1:1: upper expects 1 argument, got 2"},
+		{`replace("a", "b")`, "1:1: replace expects 3 arguments, got 2"},
+		{`split("a", ",", 1)`, "1:1: split expects 1 to 2 arguments, got 3"},
+		{`join("abc")`, "1:1: Could not get list from \"abc\""},
+		{`substring("abc", 2, 5)`, "1:1: Substring [2:5] out of range for string of length 3"},
+		{`substring("abc", 0.5)`, "1:1: Expected a whole number, got 0.5"},
+		{`repeat("a", -1)`, "1:1: Cannot repeat a string -1 times"},
+		{`repeat("a", 1e18)`, "1:1: Cannot repeat a string of length 1 1e+18 times, the result would be too long"},
+		{`char(-1)`, "1:1: Invalid character code -1"},
+		{`ord("ab")`, "1:1: ord expects a single character, got \"ab\""},
+		{`ord("")`, "1:1: ord expects a single character, got \"\""},
+	}
+
+	for _, test := range errTests {
+		_, err := interp.Run(test.code)
+		assert.EqualError(t, err, test.err, test.code)
+	}
+}